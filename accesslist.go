@@ -0,0 +1,129 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/umbracle/ethgo"
+)
+
+// AccessEntry is an eip-2930 access list entry, the address and the storage
+// keys of it that the transaction is going to access.
+type AccessEntry struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// AccessList is an eip-2930 access list.
+type AccessList []AccessEntry
+
+// toEthgo converts the access list to its ethgo representation.
+func (a AccessList) toEthgo() ethgo.AccessList {
+	if len(a) == 0 {
+		return nil
+	}
+
+	list := make(ethgo.AccessList, len(a))
+	for i, e := range a {
+		list[i] = ethgo.AccessEntry{
+			Address: ethgo.HexToAddress(e.Address),
+			Storage: make([]ethgo.Hash, len(e.StorageKeys)),
+		}
+		for j, k := range e.StorageKeys {
+			list[i].Storage[j] = ethgo.HexToHash(k)
+		}
+	}
+
+	return list
+}
+
+// AccessListResult is the result of eth_createAccessList.
+type AccessListResult struct {
+	AccessList AccessList
+	GasUsed    uint64
+	Error      string
+}
+
+// CreateAccessList returns the access list the given transaction would use
+// and the gas used by the transaction with it.
+func (c *Client) CreateAccessList(tx Transaction) (*AccessListResult, error) {
+	msg, err := c.callMsg(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		AccessList AccessList `json:"accessList"`
+		GasUsed    string     `json:"gasUsed"`
+		Error      string     `json:"error"`
+	}
+	out, err := rpcCall(c, "eth_createAccessList", func(ctx context.Context, e *endpoint) (*result, error) {
+		var out result
+		err := e.call(ctx, "eth_createAccessList", &out, msg, ethgo.Pending.String())
+		return &out, err
+	})
+	if err != nil {
+		return nil, c.wrapError("eth_createAccessList", err)
+	}
+
+	gasUsed, err := strconv.ParseUint(out.GasUsed, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode gas used: %w", err)
+	}
+
+	return &AccessListResult{
+		AccessList: out.AccessList,
+		GasUsed:    gasUsed,
+		Error:      out.Error,
+	}, nil
+}
+
+// callMsg returns the call object of tx as sent to eth_call like methods,
+// including its access list which ethgo.CallMsg does not support.
+func (c *Client) callMsg(tx Transaction) (map[string]interface{}, error) {
+	value, err := tx.Value.Int()
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, err := tx.GasPrice.Uint64()
+	if err != nil {
+		return nil, err
+	}
+
+	// from is an account of the client, the first one if not set, or any
+	// address. It's looked up without picking, calls don't move the round robin.
+	who := strings.TrimSpace(tx.From)
+	if who == "" {
+		who = "0"
+	}
+	var from ethgo.Address
+	if acc, err := c.accounts.lookup(who); err == nil {
+		from = acc.key.Address()
+	} else if tx.From != "" {
+		from = ethgo.HexToAddress(tx.From)
+	}
+
+	raw, err := json.Marshal(&ethgo.CallMsg{
+		From:     from,
+		To:       tx.to(),
+		Value:    value,
+		Data:     tx.data(),
+		GasPrice: gasPrice,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return nil, err
+	}
+	if len(tx.AccessList) > 0 {
+		msg["accessList"] = tx.AccessList
+	}
+
+	return msg, nil
+}
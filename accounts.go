@@ -0,0 +1,154 @@
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/tyler-smith/go-bip39"
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/wallet"
+)
+
+// Strategies to pick the account signing a transaction without from.
+const (
+	accountStrategyFirst      = "first"
+	accountStrategyRoundRobin = "round-robin"
+)
+
+// defaultDerivationPath is the path the index of every account is appended to.
+const defaultDerivationPath = "m/44'/60'/0'/0"
+
+var errNoAccount = errors.New("the client has no account, set the mnemonic or privateKey option")
+
+// account is a key of the client with its own nonce manager, so transactions
+// of different accounts don't wait for each other.
+type account struct {
+	key    *wallet.Key
+	nonces *nonceManager
+}
+
+// accountPool holds the accounts of the client.
+type accountPool struct {
+	strategy string
+	accounts []*account
+	next     uint64
+}
+
+func newAccountPool(strategy string, accounts []*account) (*accountPool, error) {
+	switch strategy {
+	case "":
+		strategy = accountStrategyFirst
+	case accountStrategyFirst, accountStrategyRoundRobin:
+	default:
+		return nil, fmt.Errorf("unknown account strategy %s", strategy)
+	}
+
+	return &accountPool{strategy: strategy, accounts: accounts}, nil
+}
+
+// pick returns the account with the given index or address, or the one
+// picked by the strategy if from is empty.
+func (p *accountPool) pick(from string) (*account, error) {
+	if p == nil || len(p.accounts) == 0 {
+		return nil, errNoAccount
+	}
+
+	from = strings.TrimSpace(from)
+	if from == "" {
+		if p.strategy == accountStrategyRoundRobin {
+			n := atomic.AddUint64(&p.next, 1) - 1
+			return p.accounts[n%uint64(len(p.accounts))], nil
+		}
+		return p.accounts[0], nil
+	}
+
+	return p.lookup(from)
+}
+
+// lookup returns the account with the given index or address.
+func (p *accountPool) lookup(from string) (*account, error) {
+	if p == nil || len(p.accounts) == 0 {
+		return nil, errNoAccount
+	}
+
+	if i, err := strconv.Atoi(from); err == nil {
+		if i < 0 || i >= len(p.accounts) {
+			return nil, fmt.Errorf("account index %d out of range, the client has %d accounts", i, len(p.accounts))
+		}
+		return p.accounts[i], nil
+	}
+
+	address := ethgo.HexToAddress(from)
+	for _, a := range p.accounts {
+		if a.key.Address() == address {
+			return a, nil
+		}
+	}
+
+	return nil, fmt.Errorf("account %s not found in the client", from)
+}
+
+// deriveKeys returns count keys of the mnemonic, the index of every key is
+// appended to path.
+func deriveKeys(mnemonic, path string, count int) ([]*wallet.Key, error) {
+	if path == "" {
+		path = defaultDerivationPath
+	}
+	base, err := parseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*wallet.Key, count)
+	for i := range keys {
+		p := append(append(wallet.DerivationPath{}, base...), uint32(i))
+		priv, err := p.Derive(master)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = wallet.NewKey(priv)
+	}
+
+	return keys, nil
+}
+
+// parseDerivationPath parses paths like m/44'/60'/0'/0, ' marks hardened indexes.
+func parseDerivationPath(path string) (wallet.DerivationPath, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if strings.TrimSpace(parts[0]) != "m" {
+		return nil, fmt.Errorf("invalid derivation path %s, it must start with m", path)
+	}
+
+	var result wallet.DerivationPath
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+
+		var hardened uint32
+		if strings.HasSuffix(part, "'") {
+			part = strings.TrimSuffix(part, "'")
+			hardened = hdkeychain.HardenedKeyStart
+		}
+
+		n, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path %s: %w", path, err)
+		}
+		result = append(result, uint32(n)+hardened)
+	}
+
+	return result, nil
+}
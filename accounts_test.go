@@ -0,0 +1,110 @@
+package ethereum
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/wallet"
+)
+
+const testMnemonic = "test test test test test test test test test test test junk"
+
+func Test_deriveKeys(t *testing.T) {
+	keys, err := deriveKeys(testMnemonic, "", 3)
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	require.Equal(t, ethgo.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"), keys[0].Address())
+	require.Equal(t, ethgo.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"), keys[1].Address())
+	require.Equal(t, ethgo.HexToAddress("0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"), keys[2].Address())
+
+	// the first account is the one of the default wallet
+	w, err := wallet.NewWalletFromMnemonic(testMnemonic)
+	require.NoError(t, err)
+	require.Equal(t, w.Address(), keys[0].Address())
+
+	other, err := deriveKeys(testMnemonic, "m/44'/60'/1'/0", 1)
+	require.NoError(t, err)
+	require.NotEqual(t, keys[0].Address(), other[0].Address())
+
+	_, err = deriveKeys(testMnemonic, "44'/60'", 1)
+	require.Error(t, err)
+	_, err = deriveKeys("not a mnemonic", "", 1)
+	require.Error(t, err)
+}
+
+func Test_parseDerivationPath(t *testing.T) {
+	path, err := parseDerivationPath("m/44'/60'/0'/0")
+	require.NoError(t, err)
+	require.Equal(t, wallet.DefaultDerivationPath[:4], path)
+
+	_, err = parseDerivationPath("m/44'/x")
+	require.Error(t, err)
+}
+
+func Test_accountPool(t *testing.T) {
+	keys, err := deriveKeys(testMnemonic, "", 3)
+	require.NoError(t, err)
+	accounts := make([]*account, len(keys))
+	for i, k := range keys {
+		accounts[i] = &account{key: k}
+	}
+
+	_, err = newAccountPool("random", accounts)
+	require.Error(t, err)
+
+	p, err := newAccountPool("", accounts)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		a, err := p.pick("")
+		require.NoError(t, err)
+		require.Equal(t, accounts[0], a)
+	}
+
+	p, err = newAccountPool(accountStrategyRoundRobin, accounts)
+	require.NoError(t, err)
+	for i := 0; i < 6; i++ {
+		a, err := p.pick("")
+		require.NoError(t, err)
+		require.Equal(t, accounts[i%3], a)
+	}
+
+	// from is an index or an address
+	a, err := p.pick("2")
+	require.NoError(t, err)
+	require.Equal(t, accounts[2], a)
+	a, err = p.pick(keys[1].Address().String())
+	require.NoError(t, err)
+	require.Equal(t, accounts[1], a)
+
+	_, err = p.pick("3")
+	require.Error(t, err)
+	_, err = p.pick("0x0000000000000000000000000000000000000042")
+	require.Error(t, err)
+
+	_, err = (*accountPool)(nil).pick("")
+	require.ErrorIs(t, err, errNoAccount)
+}
+
+func Test_callMsgFrom(t *testing.T) {
+	keys, err := deriveKeys(testMnemonic, "", 2)
+	require.NoError(t, err)
+	p, err := newAccountPool(accountStrategyRoundRobin, []*account{{key: keys[0]}, {key: keys[1]}})
+	require.NoError(t, err)
+	c := &Client{accounts: p}
+
+	// calls are sent from the first account and don't move the round robin
+	for i := 0; i < 3; i++ {
+		msg, err := c.callMsg(Transaction{})
+		require.NoError(t, err)
+		require.Equal(t, strings.ToLower(keys[0].Address().String()), strings.ToLower(msg["from"].(string)))
+	}
+	a, err := p.pick("")
+	require.NoError(t, err)
+	require.Equal(t, keys[0], a.key)
+
+	msg, err := c.callMsg(Transaction{From: "1"})
+	require.NoError(t, err)
+	require.Equal(t, strings.ToLower(keys[1].Address().String()), strings.ToLower(msg["from"].(string)))
+}
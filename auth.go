@@ -0,0 +1,102 @@
+package ethereum
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// authOptions configures how the client authenticates to the node, only one
+// of them can be set.
+type authOptions struct {
+	Basic *basicAuth `json:"basic,omitempty"`
+	// Bearer is a static bearer token
+	Bearer string `json:"bearer,omitempty"`
+	// JWTSecret is the hex encoded secret of HS256 tokens as used by the Engine API
+	JWTSecret string `json:"jwtSecret,omitempty"`
+}
+
+type basicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// jwtSecret returns the decoded JWT secret, nil if not set.
+func (a *authOptions) jwtSecret() ([]byte, error) {
+	if a == nil || a.JWTSecret == "" {
+		return nil, nil
+	}
+
+	secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(a.JWTSecret), "0x"))
+	if err != nil || len(secret) == 0 {
+		return nil, errors.New("jwtSecret must be a hex encoded secret")
+	}
+
+	return secret, nil
+}
+
+func (a *authOptions) validate() error {
+	if a == nil {
+		return nil
+	}
+
+	set := 0
+	for _, ok := range []bool{a.Basic != nil, a.Bearer != "", a.JWTSecret != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set > 1 {
+		return errors.New("only one of basic, bearer or jwtSecret auth can be set")
+	}
+
+	_, err := a.jwtSecret()
+	return err
+}
+
+// requestHeaders returns the headers of a request to the node, including a
+// freshly minted JWT token.
+func (o *options) requestHeaders() (map[string]string, error) {
+	headers := map[string]string{}
+	for k, v := range o.Headers {
+		headers[k] = v
+	}
+
+	if o.Auth == nil {
+		return headers, nil
+	}
+
+	switch {
+	case o.Auth.Basic != nil:
+		creds := o.Auth.Basic.Username + ":" + o.Auth.Basic.Password
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+	case o.Auth.Bearer != "":
+		headers["Authorization"] = "Bearer " + o.Auth.Bearer
+	case o.Auth.JWTSecret != "":
+		secret, err := o.Auth.jwtSecret()
+		if err != nil {
+			return nil, err
+		}
+		headers["Authorization"] = "Bearer " + mintJWT(secret, time.Now())
+	}
+
+	return headers, nil
+}
+
+// mintJWT returns an HS256 token issued at now, the only claim required by
+// the Engine API.
+func mintJWT(secret []byte, now time.Time) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims := enc.EncodeToString([]byte(`{"iat":` + strconv.FormatInt(now.Unix(), 10) + `}`))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + claims))
+
+	return header + "." + claims + "." + enc.EncodeToString(mac.Sum(nil))
+}
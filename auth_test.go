@@ -0,0 +1,71 @@
+package ethereum
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mintJWT(t *testing.T) {
+	secret := []byte("secret")
+	token := mintJWT(secret, time.Unix(1700000000, 0))
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	require.Equal(t, `{"iat":1700000000}`, string(claims))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), parts[2])
+}
+
+func Test_requestHeaders(t *testing.T) {
+	opts := &options{
+		Headers: map[string]string{"X-Api-Key": "key"},
+		Auth:    &authOptions{Basic: &basicAuth{Username: "user", Password: "pass"}},
+	}
+	headers, err := opts.requestHeaders()
+	require.NoError(t, err)
+	require.Equal(t, "key", headers["X-Api-Key"])
+	require.Equal(t, "Basic dXNlcjpwYXNz", headers["Authorization"])
+
+	require.Error(t, (&authOptions{Bearer: "token", JWTSecret: "00"}).validate())
+	require.Error(t, (&authOptions{JWTSecret: "not hex"}).validate())
+}
+
+func Test_endpointJWT(t *testing.T) {
+	node := newTestNode(t)
+	node.handle("eth_blockNumber", result(`"0x1"`))
+	c := newTestClient(t, node.url)
+	c.opts.Auth = &authOptions{JWTSecret: "0x" + strings.Repeat("ab", 32)}
+
+	issuedAt := func(i int) int64 {
+		token := node.headers[i].Get("Authorization")
+		require.True(t, strings.HasPrefix(token, "Bearer ey"))
+
+		claims, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+		require.NoError(t, err)
+		var iat struct{ Iat int64 }
+		require.NoError(t, json.Unmarshal(claims, &iat))
+		return iat.Iat
+	}
+
+	_, err := c.BlockNumber()
+	require.NoError(t, err)
+	first := issuedAt(0)
+
+	// every request is sent with a token minted for it
+	time.Sleep(time.Until(time.Unix(first+1, 0)))
+	_, err = c.BlockNumber()
+	require.NoError(t, err)
+	require.Greater(t, issuedAt(1), first)
+}
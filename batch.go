@@ -0,0 +1,120 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+)
+
+var errBatchRequiresHTTP = errors.New("batch requests require an http url")
+
+// BatchRequest is a request of a JSON-RPC batch.
+type BatchRequest struct {
+	Method string
+	Params []interface{}
+}
+
+// BatchResult is the outcome of a batch request, either the result or the error.
+type BatchResult struct {
+	Result interface{}
+	Error  *RPCError
+}
+
+// Batch sends the requests in a single JSON-RPC batch and returns their
+// results in the same order. A failed request doesn't fail the batch, its
+// error is set in its result.
+func (c *Client) Batch(requests []BatchRequest) ([]*BatchResult, error) {
+	if c.ws != nil {
+		return nil, errBatchRequiresHTTP
+	}
+	if len(requests) == 0 {
+		return []*BatchResult{}, nil
+	}
+
+	batch := make([]codec.Request, len(requests))
+	for i, r := range requests {
+		params := r.Params
+		if params == nil {
+			params = []interface{}{}
+		}
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid params of request %d: %w", i, err)
+		}
+		batch[i] = codec.Request{JsonRPC: "2.0", ID: uint64(i), Method: r.Method, Params: raw}
+	}
+
+	tags := map[string]string{"batch_size": strconv.Itoa(len(requests))}
+	responses, err := rpcCallWithTags(c, "batch", tags, func(ctx context.Context, e *endpoint) ([]codec.Response, error) {
+		return e.postBatch(ctx, batch)
+	})
+	if err != nil {
+		return nil, c.wrapError("batch", err)
+	}
+
+	results := make([]*BatchResult, len(requests))
+	for _, resp := range responses {
+		if resp.ID >= uint64(len(results)) {
+			continue
+		}
+		method := requests[resp.ID].Method
+
+		if resp.Error != nil {
+			e := newRPCError(method, resp.Error, nil)
+			c.reportError(e)
+			results[resp.ID] = &BatchResult{Error: e}
+			continue
+		}
+
+		// a response without a result is a null one
+		var out interface{}
+		if len(resp.Result) > 0 {
+			if err := json.Unmarshal(resp.Result, &out); err != nil {
+				e := newRPCError(method, err, nil)
+				c.reportError(e)
+				results[resp.ID] = &BatchResult{Error: e}
+				continue
+			}
+		}
+		results[resp.ID] = &BatchResult{Result: out}
+	}
+
+	for i, r := range results {
+		if r == nil {
+			e := newRPCError(requests[i].Method, errors.New("missing response in batch"), nil)
+			c.reportError(e)
+			results[i] = &BatchResult{Error: e}
+		}
+	}
+
+	return results, nil
+}
+
+// postBatch sends a JSON-RPC batch to the endpoint over http and returns its
+// responses.
+func (e *endpoint) postBatch(ctx context.Context, batch []codec.Request) ([]codec.Response, error) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+	raw, status, err := e.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []codec.Response
+	if err := json.Unmarshal(raw, &responses); err != nil {
+		// nodes answer a batch they can't process with a single error
+		var single codec.Response
+		if json.Unmarshal(raw, &single) == nil && single.Error != nil {
+			return nil, single.Error
+		}
+		return nil, fmt.Errorf("invalid batch response, status %d: %w", status, err)
+	}
+
+	return responses, nil
+}
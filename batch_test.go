@@ -0,0 +1,36 @@
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+)
+
+func Test_Batch(t *testing.T) {
+	node := newTestNode(t)
+	node.handle("eth_getBalance", result(`"0x10"`))
+	// some nodes leave out null results
+	node.handle("eth_getTransactionByHash", result(""))
+	node.handle("eth_foo", func(*rpcRequest) (string, error) {
+		return "", &codec.ErrorObject{Code: -32601, Message: "method not found"}
+	})
+
+	c := newTestClient(t, node.url)
+	c.opts.Auth = &authOptions{Bearer: "token"}
+	results, err := c.Batch([]BatchRequest{
+		{Method: "eth_getBalance", Params: []interface{}{"0x85da99c8a7c2c95964c8efd687e95e632fc533d6", "latest"}},
+		{Method: "eth_foo"},
+		{Method: "eth_getTransactionByHash", Params: []interface{}{"0x01"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, node.batches)
+	require.Equal(t, "Bearer token", node.headers[0].Get("Authorization"))
+	require.Len(t, results, 3)
+	require.Equal(t, "0x10", results[0].Result)
+	require.Nil(t, results[0].Error)
+	require.Equal(t, "eth_foo", results[1].Error.Method)
+	require.Equal(t, errClassRPC, results[1].Error.Class)
+	require.Nil(t, results[2].Result)
+	require.Nil(t, results[2].Error)
+}
@@ -0,0 +1,331 @@
+package ethereum
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/wallet"
+	"github.com/umbracle/fastrlp"
+)
+
+const (
+	blobTxType = 0x03
+	// bytesPerBlobPayload is the amount of payload packed in a blob, 31 bytes
+	// per field element so every element is below the field modulus.
+	bytesPerBlobPayload = fieldElementsPerBlob * (bytesPerFieldElement - 1)
+)
+
+var errTrustedSetupRequired = errors.New("blob transactions require the trustedSetup option")
+
+// isBlob returns true if tx carries blobs.
+func (tx Transaction) isBlob() bool {
+	return len(tx.Blobs) > 0 || tx.BlobCount > 0
+}
+
+// blobs returns the blobs of tx, the given payloads followed by BlobCount
+// random blobs.
+func (tx Transaction) blobs() ([][]byte, error) {
+	blobs := make([][]byte, 0, len(tx.Blobs)+tx.BlobCount)
+	for i, b := range tx.Blobs {
+		blob, err := blobFromBytes(decodeHexBytes(b))
+		if err != nil {
+			return nil, fmt.Errorf("invalid blob %d: %w", i, err)
+		}
+		blobs = append(blobs, blob)
+	}
+
+	for i := 0; i < tx.BlobCount; i++ {
+		blob, err := randomBlob()
+		if err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, blob)
+	}
+
+	return blobs, nil
+}
+
+// blobFromBytes returns b as a blob. A full sized b is used as is, any other
+// payload is packed 31 bytes per field element.
+func blobFromBytes(b []byte) ([]byte, error) {
+	if len(b) == blobSize {
+		return b, nil
+	}
+	if len(b) > bytesPerBlobPayload {
+		return nil, fmt.Errorf("payload of %d bytes exceeds %d bytes", len(b), bytesPerBlobPayload)
+	}
+
+	blob := make([]byte, blobSize)
+	for i := 0; len(b) > 0; i++ {
+		n := copy(blob[i*bytesPerFieldElement+1:(i+1)*bytesPerFieldElement], b)
+		b = b[n:]
+	}
+
+	return blob, nil
+}
+
+// randomBlob returns a blob of random field elements.
+func randomBlob() ([]byte, error) {
+	blob := make([]byte, blobSize)
+	if _, err := rand.Read(blob); err != nil {
+		return nil, fmt.Errorf("failed to generate blob: %w", err)
+	}
+	// a zero leading byte keeps every element below the field modulus
+	for i := 0; i < blobSize; i += bytesPerFieldElement {
+		blob[i] = 0
+	}
+
+	return blob, nil
+}
+
+// blobSidecar holds the blobs of a transaction with their KZG commitments and proofs.
+type blobSidecar struct {
+	blobs       [][]byte
+	commitments [][]byte
+	proofs      [][]byte
+}
+
+func newBlobSidecar(setup *kzgSetup, blobs [][]byte) (*blobSidecar, error) {
+	s := &blobSidecar{
+		blobs:       blobs,
+		commitments: make([][]byte, len(blobs)),
+		proofs:      make([][]byte, len(blobs)),
+	}
+
+	for i, blob := range blobs {
+		commitment, err := setup.blobToCommitment(blob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blob %d: %w", i, err)
+		}
+		proof, err := setup.computeBlobProof(blob, commitment)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blob %d: %w", i, err)
+		}
+		s.commitments[i] = commitment
+		s.proofs[i] = proof
+	}
+
+	return s, nil
+}
+
+// versionedHashes returns the versioned hashes of the blob commitments.
+func (s *blobSidecar) versionedHashes() []ethgo.Hash {
+	hashes := make([]ethgo.Hash, len(s.commitments))
+	for i, c := range s.commitments {
+		hashes[i] = blobVersionedHash(c)
+	}
+
+	return hashes
+}
+
+// blobTx is an eip-4844 transaction, which ethgo doesn't support.
+type blobTx struct {
+	chainID    *big.Int
+	nonce      uint64
+	gasTipCap  *big.Int
+	gasFeeCap  *big.Int
+	gas        uint64
+	to         ethgo.Address
+	value      *big.Int
+	data       []byte
+	accessList ethgo.AccessList
+	blobFeeCap *big.Int
+	blobHashes []ethgo.Hash
+	sidecar    *blobSidecar
+
+	v    uint64
+	r, s *big.Int
+}
+
+// marshalFields returns the rlp list of the transaction fields, including the
+// signature when signed is true.
+func (t *blobTx) marshalFields(a *fastrlp.Arena, signed bool) (*fastrlp.Value, error) {
+	v := a.NewArray()
+	v.Set(a.NewBigInt(t.chainID))
+	v.Set(a.NewUint(t.nonce))
+	v.Set(a.NewBigInt(t.gasTipCap))
+	v.Set(a.NewBigInt(t.gasFeeCap))
+	v.Set(a.NewUint(t.gas))
+	v.Set(a.NewCopyBytes(t.to[:]))
+	v.Set(a.NewBigInt(t.value))
+	v.Set(a.NewCopyBytes(t.data))
+
+	accessList, err := t.accessList.MarshalRLPWith(a)
+	if err != nil {
+		return nil, err
+	}
+	v.Set(accessList)
+
+	v.Set(a.NewBigInt(t.blobFeeCap))
+	hashes := a.NewArray()
+	for _, h := range t.blobHashes {
+		hashes.Set(a.NewCopyBytes(h[:]))
+	}
+	v.Set(hashes)
+
+	if signed {
+		v.Set(a.NewUint(t.v))
+		v.Set(a.NewBigInt(t.r))
+		v.Set(a.NewBigInt(t.s))
+	}
+
+	return v, nil
+}
+
+// sign signs the transaction with key.
+func (t *blobTx) sign(key *wallet.Key) error {
+	a := fastrlp.DefaultArenaPool.Get()
+	defer fastrlp.DefaultArenaPool.Put(a)
+
+	v, err := t.marshalFields(a, false)
+	if err != nil {
+		return err
+	}
+
+	hash := ethgo.Keccak256(v.MarshalTo([]byte{blobTxType}))
+	sig, err := key.Sign(hash)
+	if err != nil {
+		return fmt.Errorf("failed to sign tx: %w", err)
+	}
+
+	t.r = new(big.Int).SetBytes(sig[:32])
+	t.s = new(big.Int).SetBytes(sig[32:64])
+	t.v = uint64(sig[64])
+
+	return nil
+}
+
+// hash returns the hash of the signed transaction, without its blobs.
+func (t *blobTx) hash() (ethgo.Hash, error) {
+	a := fastrlp.DefaultArenaPool.Get()
+	defer fastrlp.DefaultArenaPool.Put(a)
+
+	v, err := t.marshalFields(a, true)
+	if err != nil {
+		return ethgo.Hash{}, err
+	}
+
+	return ethgo.BytesToHash(ethgo.Keccak256(v.MarshalTo([]byte{blobTxType}))), nil
+}
+
+// marshalNetwork returns the signed transaction wrapped with its blobs,
+// commitments and proofs as expected by eth_sendRawTransaction.
+func (t *blobTx) marshalNetwork() ([]byte, error) {
+	a := fastrlp.DefaultArenaPool.Get()
+	defer fastrlp.DefaultArenaPool.Put(a)
+
+	fields, err := t.marshalFields(a, true)
+	if err != nil {
+		return nil, err
+	}
+
+	v := a.NewArray()
+	v.Set(fields)
+	for _, items := range [][][]byte{t.sidecar.blobs, t.sidecar.commitments, t.sidecar.proofs} {
+		list := a.NewArray()
+		for _, item := range items {
+			list.Set(a.NewCopyBytes(item))
+		}
+		v.Set(list)
+	}
+
+	return v.MarshalTo([]byte{blobTxType}), nil
+}
+
+// sendBlobTransaction signs and sends a blob transaction.
+func (c *Client) sendBlobTransaction(key *wallet.Key, tx Transaction, gas uint64) (string, error) {
+	if c.kzg == nil {
+		return "", errTrustedSetupRequired
+	}
+
+	to := tx.to()
+	if to == nil {
+		return "", errors.New("blob transactions can't create contracts")
+	}
+
+	// fees are taken from the gas price when no fee caps are given
+	t := &ethgo.Transaction{}
+	if err := tx.setAmounts(t); err != nil {
+		return "", err
+	}
+	if t.Type != ethgo.TransactionDynamicFee {
+		t.MaxFeePerGas = new(big.Int).SetUint64(t.GasPrice)
+		t.MaxPriorityFeePerGas = new(big.Int).SetUint64(t.GasPrice)
+	}
+
+	blobFeeCap, err := tx.MaxFeePerBlobGas.Int()
+	if err != nil {
+		return "", err
+	}
+	if blobFeeCap.Sign() == 0 {
+		if blobFeeCap, err = c.blobBaseFee(); err != nil {
+			return "", err
+		}
+		blobFeeCap.Mul(blobFeeCap, big.NewInt(2))
+	}
+
+	blobs, err := tx.blobs()
+	if err != nil {
+		return "", err
+	}
+	sidecar, err := newBlobSidecar(c.kzg, blobs)
+	if err != nil {
+		return "", err
+	}
+
+	btx := &blobTx{
+		chainID:    c.chainID,
+		nonce:      tx.Nonce,
+		gasTipCap:  t.MaxPriorityFeePerGas,
+		gasFeeCap:  t.MaxFeePerGas,
+		gas:        gas,
+		to:         *to,
+		value:      t.Value,
+		data:       tx.data(),
+		accessList: t.AccessList,
+		blobFeeCap: blobFeeCap,
+		blobHashes: sidecar.versionedHashes(),
+		sidecar:    sidecar,
+	}
+	if err := btx.sign(key); err != nil {
+		return "", err
+	}
+
+	raw, err := btx.marshalNetwork()
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tx: %w", err)
+	}
+
+	hash, err := btx.hash()
+	if err != nil {
+		return "", err
+	}
+
+	sent := time.Now()
+	h, err := c.sendSigned(raw, hash)
+	if err != nil {
+		return "", err
+	}
+	c.submitted(h, "blob", sent)
+
+	return h.String(), nil
+}
+
+// blobBaseFee returns the blob base fee of the next block.
+func (c *Client) blobBaseFee() (*big.Int, error) {
+	out, err := rpcCall(c, "eth_blobBaseFee", func(ctx context.Context, e *endpoint) (string, error) {
+		var out string
+		err := e.call(ctx, "eth_blobBaseFee", &out)
+		return out, err
+	})
+	if err != nil {
+		return nil, c.wrapError("eth_blobBaseFee", err)
+	}
+
+	return Wei(out).Int()
+}
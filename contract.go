@@ -1,51 +1,225 @@
 package ethereum
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"time"
 
+	"github.com/grafana/sobek"
 	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/abi"
 	"github.com/umbracle/ethgo/contract"
 )
 
 // Contract exposes a contract
 type Contract struct {
 	*contract.Contract
-	client *Client
+	address ethgo.Address
+	client  *Client
 }
 
 type TxnOpts struct {
-	Value    uint64
-	GasPrice uint64
+	Value    Wei
+	GasPrice Wei
 	GasLimit uint64
 	Nonce    uint64
+	// eip-2930 values
+	AccessList AccessList
 }
 
-// Call executes a call on the contract
+// Call executes a call on the contract, uint and int outputs are returned as decimal strings
 func (c *Contract) Call(method string, args ...interface{}) (map[string]interface{}, error) {
-	return c.Contract.Call(method, ethgo.Latest, args...)
+	out, err := rpcCall(c.client, "eth_call", func(ctx context.Context, e *endpoint) (map[string]interface{}, error) {
+		return c.on(ctx, e).Call(method, ethgo.Latest, args...)
+	})
+	if err != nil {
+		return nil, c.client.wrapContractError("eth_call", err, c.GetABI())
+	}
+
+	for k, v := range out {
+		out[k] = bigIntsToStrings(v)
+	}
+
+	return out, nil
 }
 
-// Txn executes a transactions on the contract and waits for it to be mined
-// TODO maybe use promise
+// TxnReceipt is the receipt of a contract transaction with the events it
+// emitted and, if it failed, the revert reason.
+type TxnReceipt struct {
+	*Receipt
+	Events []*Event
+	Revert *Revert
+}
+
+// Txn sends a transaction on the contract and returns its hash. It's signed
+// by an account of the client like the ones of sendRawTransaction, with its
+// nonce manager and fees.
 func (c *Contract) Txn(method string, opts TxnOpts, args ...interface{}) (string, error) {
-	txn, err := c.Contract.Txn(method, args...)
+	m := c.Contract.GetABI().GetMethod(method)
+	if m == nil {
+		return "", fmt.Errorf("failed to create contract transaction: method %s not found", method)
+	}
+
+	input, err := m.Encode(args)
 	if err != nil {
 		return "", fmt.Errorf("failed to create contract transaction: %w", err)
 	}
 
-	txo := contract.TxnOpts{
-		Value:    big.NewInt(int64(opts.Value)),
-		GasPrice: opts.GasPrice,
-		GasLimit: opts.GasLimit,
-		Nonce:    opts.Nonce,
+	return c.client.sendContractTxn(Transaction{
+		To:         c.address.String(),
+		Value:      opts.Value,
+		GasPrice:   opts.GasPrice,
+		Gas:        opts.GasLimit,
+		Nonce:      opts.Nonce,
+		AccessList: opts.AccessList,
+		calldata:   input,
+	}, c.GetABI())
+}
+
+// on returns the contract bound to endpoint e, calls are sent from the first
+// account.
+func (c *Contract) on(ctx context.Context, e *endpoint) *contract.Contract {
+	return contract.NewContract(c.address, c.GetABI(),
+		contract.WithProvider(contractProvider{ctx, e}),
+		contract.WithSender(c.client.w),
+	)
+}
+
+// contractProvider sends the calls of a contract to an endpoint. Its
+// transactions are sent by the client, with the accounts, nonces and fees of
+// sendRawTransaction.
+type contractProvider struct {
+	ctx context.Context
+	e   *endpoint
+}
+
+func (p contractProvider) Call(to ethgo.Address, input []byte, opts *contract.CallOpts) ([]byte, error) {
+	msg := &ethgo.CallMsg{To: &to, Data: input}
+	if opts.From != ethgo.ZeroAddress {
+		msg.From = opts.From
+	}
+
+	var out ethgo.ArgBytes
+	if err := p.e.call(p.ctx, "eth_call", &out, msg, opts.Block.String()); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (p contractProvider) Txn(ethgo.Address, ethgo.Key, []byte) (contract.Txn, error) {
+	return nil, errors.New("contract transactions are sent with txn")
+}
+
+// TxnAsync sends a transaction on the contract and returns a promise resolved
+// with its receipt once it's mined.
+func (c *Contract) TxnAsync(method string, opts TxnOpts, args ...interface{}) *sobek.Promise {
+	promise, resolve, reject := c.client.makeHandledPromise()
+
+	go func() {
+		hash, err := c.Txn(method, opts, args...)
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		now := time.Now()
+		receipt, err := c.client.waitForReceipt(c.client.vuContext(), hash, 0, 0)
+		if err != nil {
+			reject(err)
+			return
+		}
+		c.client.reportTimeToMine(c.client.sinceSent(hash, now), map[string]string{"method": method})
+
+		events, err := c.DecodeLogs(receipt)
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		res := &TxnReceipt{Receipt: receipt, Events: events}
+		if receipt.Status == 0 {
+			res.Revert = c.revertReason(receipt)
+		}
+		resolve(res)
+	}()
+
+	return promise
+}
+
+// revertReason replays a failed transaction with eth_call to get the revert
+// reason, as receipts don't have it. The failure is counted as an error.
+func (c *Contract) revertReason(receipt *Receipt) *Revert {
+	tx, err := rpcCall(c.client, "eth_getTransactionByHash", func(ctx context.Context, e *endpoint) (*ethgo.Transaction, error) {
+		var tx *ethgo.Transaction
+		err := e.call(ctx, "eth_getTransactionByHash", &tx, receipt.TransactionHash)
+		return tx, err
+	})
+	if err != nil || tx == nil {
+		return nil
+	}
+
+	// replayed on the state of the parent block, the closest one to the state
+	// the transaction was executed on
+	block := receipt.BlockNumber
+	if block > 0 {
+		block--
+	}
+	_, err = rpcCall(c.client, "eth_call", func(ctx context.Context, e *endpoint) (string, error) {
+		var out string
+		err := e.call(ctx, "eth_call", &out, &ethgo.CallMsg{
+			From:  tx.From,
+			To:    tx.To,
+			Data:  tx.Input,
+			Value: tx.Value,
+			Gas:   new(big.Int).SetUint64(tx.Gas),
+		}, ethgo.BlockNumber(block).String())
+		return out, err
+	})
+	if err == nil {
+		return nil
 	}
-	txn.WithOpts(&txo)
 
-	err = txn.Do()
+	e := newRPCError("eth_call", err, c.GetABI())
+	c.client.reportError(e)
+
+	return e.Revert
+}
+
+// sendContractTxn sends tx to a contract, or deploying one if it has no
+// recipient, from an account of the pool through SendRawTransaction. Its gas
+// is estimated here to decode reverts with the errors of a.
+func (c *Client) sendContractTxn(tx Transaction, a *abi.ABI) (string, error) {
+	acc, err := c.accounts.pick("")
 	if err != nil {
-		return "", fmt.Errorf("failed to send contract transaction: %w", err)
+		return "", err
+	}
+	tx.From = acc.key.Address().String()
+	if tx.Nonce == 0 && acc.nonces == nil {
+		nonce, err := c.GetNonce(tx.From)
+		if err != nil {
+			return "", err
+		}
+		tx.Nonce = nonce
+	}
+
+	// without a fee oracle the gas price of the node is used
+	if tx.GasPrice.IsZero() && c.fees == nil {
+		gasPrice, err := c.GasPrice()
+		if err != nil {
+			return "", err
+		}
+		tx.GasPrice = Wei(gasPrice)
+	}
+
+	if tx.Gas == 0 {
+		gas, err := c.estimateGas(tx)
+		if err != nil {
+			return "", c.wrapContractError("eth_estimateGas", err, a)
+		}
+		tx.Gas = gas
 	}
 
-	return txn.Hash().String(), nil
+	return c.SendRawTransaction(tx)
 }
@@ -0,0 +1,38 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/wallet"
+)
+
+func Test_contractTxn(t *testing.T) {
+	pk, _ := hex.DecodeString("42b6e34dc21598a807dc19d7784c71b2a7a01f6480dc6f58258f78e539f1a1fa")
+	wa, err := wallet.NewWalletFromPrivKey(pk)
+	require.NoError(t, err)
+	wb, err := wallet.GenerateKey()
+	require.NoError(t, err)
+
+	node := newTransferNode(t, map[ethgo.Address]*big.Int{wa.Address(): big.NewInt(1_000_000), wb.Address(): big.NewInt(1_000_000)})
+	c := newTestClient(t, node.url, wa, wb)
+
+	contract, err := c.NewContract(ethgo.ZeroAddress.String(), `[{"inputs":[{"name":"n","type":"uint256"}],"name":"set","outputs":[],"stateMutability":"nonpayable","type":"function"}]`)
+	require.NoError(t, err)
+
+	_, err = contract.Txn("unknown", TxnOpts{GasLimit: 30000})
+	require.ErrorContains(t, err, "method unknown not found")
+
+	// signed by the accounts of the pool in turn
+	for i := 0; i < 2; i++ {
+		_, err := contract.Txn("set", TxnOpts{GasLimit: 30000}, 1)
+		require.NoError(t, err)
+	}
+	require.Len(t, node.nonces[wa.Address()], 1)
+	require.Len(t, node.nonces[wb.Address()], 1)
+	// at the gas price of the node
+	require.Equal(t, big.NewInt(1_000_000-30000), node.balances[wb.Address()])
+}
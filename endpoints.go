@@ -0,0 +1,281 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/jsonrpc"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+)
+
+// Strategies to pick the endpoint of a call.
+const (
+	strategyRoundRobin   = "round-robin"
+	strategyRandom       = "random"
+	strategyFailover     = "failover"
+	strategyLeastLatency = "least-latency"
+)
+
+// unhealthyCooldown is how long an endpoint is skipped after failing to answer.
+const unhealthyCooldown = 5 * time.Second
+
+// endpoint is a node the client sends calls to.
+type endpoint struct {
+	url string
+	// http sends the requests to http urls
+	http *http.Client
+	// headers returns the headers of every http request, minting a new JWT
+	// token each time
+	headers func() (map[string]string, error)
+	// ws sends the requests to websocket urls, with the headers of the
+	// connection
+	ws *wsConn
+	// client sends the requests to ipc paths
+	client *jsonrpc.Client
+
+	lock           sync.Mutex
+	unhealthyUntil time.Time
+	// latency is a moving average of the duration of calls, 0 until the
+	// first one
+	latency time.Duration
+}
+
+// newEndpoint returns the endpoint of url, sending requests with the headers,
+// auth and timeout of opts.
+func newEndpoint(url string, opts *options) (*endpoint, error) {
+	if _, err := opts.Auth.jwtSecret(); err != nil {
+		return nil, err
+	}
+
+	e := &endpoint{
+		url: url,
+		http: &http.Client{
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+			Timeout:   time.Duration(opts.Timeout),
+		},
+		headers: opts.requestHeaders,
+	}
+	if isHTTPURL(url) {
+		return e, nil
+	}
+
+	headers, err := opts.requestHeaders()
+	if err != nil {
+		return nil, err
+	}
+	if isWebsocketURL(url) {
+		e.ws, err = dialWebsocket(url, headers)
+	} else {
+		e.client, err = jsonrpc.NewClient(url, jsonrpc.WithHeaders(headers))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func isHTTPURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// call sends a JSON-RPC request to the endpoint and decodes its result into
+// out. Http requests are sent with their own headers, so with JWT auth every
+// one of them has a fresh token. The request is abandoned once ctx is done,
+// except over ipc.
+func (e *endpoint) call(ctx context.Context, method string, out interface{}, params ...interface{}) error {
+	if e.ws != nil {
+		return e.ws.call(ctx, method, out, nil, params...)
+	}
+	if e.client != nil {
+		return e.client.Call(method, out, params...)
+	}
+
+	req := codec.Request{JsonRPC: "2.0", Method: method}
+	if len(params) > 0 {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = raw
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	raw, _, err := e.post(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	var resp codec.Response
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	return json.Unmarshal(resp.Result, out)
+}
+
+func (e *endpoint) blockNumber(ctx context.Context) (uint64, error) {
+	var n ethgo.ArgUint64
+	err := e.call(ctx, "eth_blockNumber", &n)
+	return n.Uint64(), err
+}
+
+func (e *endpoint) blockByNumber(ctx context.Context, n ethgo.BlockNumber, full bool) (*ethgo.Block, error) {
+	var b *ethgo.Block
+	err := e.call(ctx, "eth_getBlockByNumber", &b, n.String(), full)
+	return b, err
+}
+
+func (e *endpoint) gasPrice(ctx context.Context) (uint64, error) {
+	var p ethgo.ArgUint64
+	err := e.call(ctx, "eth_gasPrice", &p)
+	return p.Uint64(), err
+}
+
+// post sends body to the endpoint over http and returns the body and status
+// code of the response.
+func (e *endpoint) post(ctx context.Context, body []byte) ([]byte, int, error) {
+	headers, err := e.headers()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.http.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return raw, res.StatusCode, nil
+}
+
+// done records the outcome of a call to the endpoint, errors reaching the
+// node mark it unhealthy. Failed calls count in the latency too, an endpoint
+// quickly answering errors would otherwise stay unmeasured and be picked by
+// least-latency every time.
+func (e *endpoint) done(d time.Duration, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.latency == 0 {
+		e.latency = d
+	} else {
+		e.latency = (4*e.latency + d) / 5
+	}
+
+	switch {
+	case err == nil:
+		e.unhealthyUntil = time.Time{}
+	case isRetryable(err):
+		e.unhealthyUntil = time.Now().Add(unhealthyCooldown)
+	}
+}
+
+func (e *endpoint) healthy(now time.Time) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return !now.Before(e.unhealthyUntil)
+}
+
+func (e *endpoint) averageLatency() time.Duration {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.latency
+}
+
+// endpointPool picks the endpoint of every call according to its strategy.
+type endpointPool struct {
+	strategy  string
+	endpoints []*endpoint
+	next      uint64
+}
+
+func newEndpointPool(strategy string, endpoints []*endpoint) (*endpointPool, error) {
+	switch strategy {
+	case "":
+		strategy = strategyRoundRobin
+	case strategyRoundRobin, strategyRandom, strategyFailover, strategyLeastLatency:
+	default:
+		return nil, fmt.Errorf("unknown strategy %s", strategy)
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoints")
+	}
+
+	return &endpointPool{strategy: strategy, endpoints: endpoints}, nil
+}
+
+// pick returns the endpoint of the next call among the healthy ones, or
+// among all of them if none is healthy.
+func (p *endpointPool) pick() *endpoint {
+	if len(p.endpoints) == 1 {
+		return p.endpoints[0]
+	}
+
+	now := time.Now()
+	candidates := make([]*endpoint, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		if e.healthy(now) {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = p.endpoints
+	}
+
+	switch p.strategy {
+	case strategyRandom:
+		return candidates[rand.Intn(len(candidates))]
+	case strategyFailover:
+		return candidates[0]
+	case strategyLeastLatency:
+		best := candidates[0]
+		for _, e := range candidates[1:] {
+			// endpoints without calls yet are picked first to measure them
+			if l := e.averageLatency(); l < best.averageLatency() {
+				best = e
+			}
+		}
+		return best
+	default:
+		n := atomic.AddUint64(&p.next, 1) - 1
+		return candidates[n%uint64(len(candidates))]
+	}
+}
+
+// primary returns the first endpoint, used by the block monitor and subscriptions.
+func (p *endpointPool) primary() *endpoint {
+	return p.endpoints[0]
+}
@@ -0,0 +1,68 @@
+package ethereum
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+)
+
+func Test_endpointPool(t *testing.T) {
+	newPool := func(strategy string) (*endpointPool, []*endpoint) {
+		endpoints := []*endpoint{{url: "a"}, {url: "b"}, {url: "c"}}
+		p, err := newEndpointPool(strategy, endpoints)
+		require.NoError(t, err)
+		return p, endpoints
+	}
+
+	_, err := newEndpointPool("fastest", []*endpoint{{url: "a"}})
+	require.Error(t, err)
+
+	p, endpoints := newPool("")
+	require.Equal(t, strategyRoundRobin, p.strategy)
+	require.Equal(t, "a", p.pick().url)
+	require.Equal(t, "b", p.pick().url)
+	require.Equal(t, "c", p.pick().url)
+
+	// errors answered by the node don't make it unhealthy
+	endpoints[1].done(time.Millisecond, &codec.ErrorObject{Code: -32000, Message: "nonce too low"})
+	require.True(t, endpoints[1].healthy(time.Now()))
+	endpoints[1].done(time.Millisecond, errors.New("connection refused"))
+	require.False(t, endpoints[1].healthy(time.Now()))
+	require.True(t, endpoints[1].healthy(time.Now().Add(unhealthyCooldown)))
+	for i := 0; i < 4; i++ {
+		require.NotEqual(t, "b", p.pick().url)
+	}
+
+	p, endpoints = newPool(strategyFailover)
+	require.Equal(t, "a", p.pick().url)
+	endpoints[0].done(time.Millisecond, errors.New("connection refused"))
+	require.Equal(t, "b", p.pick().url)
+	endpoints[0].done(time.Millisecond, nil)
+	require.Equal(t, "a", p.pick().url)
+
+	p, endpoints = newPool(strategyLeastLatency)
+	endpoints[0].done(30*time.Millisecond, nil)
+	endpoints[1].done(10*time.Millisecond, nil)
+	endpoints[2].done(20*time.Millisecond, nil)
+	require.Equal(t, "b", p.pick().url)
+	endpoints[1].done(time.Second, nil)
+	require.Equal(t, "c", p.pick().url)
+
+	// an endpoint answering only errors is measured like the others
+	p, endpoints = newPool(strategyLeastLatency)
+	endpoints[0].done(10*time.Millisecond, nil)
+	endpoints[1].done(20*time.Millisecond, nil)
+	require.Equal(t, "c", p.pick().url)
+	endpoints[2].done(50*time.Millisecond, &codec.ErrorObject{Code: -32000, Message: "execution reverted"})
+	require.Equal(t, "a", p.pick().url)
+
+	// all unhealthy falls back to all of them
+	p, endpoints = newPool(strategyRandom)
+	for _, e := range endpoints {
+		e.done(time.Millisecond, errors.New("connection refused"))
+	}
+	require.NotNil(t, p.pick())
+}
@@ -0,0 +1,240 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/umbracle/ethgo/abi"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+	"go.k6.io/k6/metrics"
+)
+
+// Error classes, tagging the ethereum_errors metric and set in RPCError.Class.
+const (
+	errClassNonceTooLow            = "nonce_too_low"
+	errClassNonceTooHigh           = "nonce_too_high"
+	errClassUnderpriced            = "underpriced"
+	errClassReplacementUnderpriced = "replacement_underpriced"
+	errClassInsufficientFunds      = "insufficient_funds"
+	errClassAlreadyKnown           = "already_known"
+	errClassExecutionReverted      = "execution_reverted"
+	errClassTimeout                = "timeout"
+	errClassTransport              = "transport"
+	// errClassRPC is any other error returned by the node
+	errClassRPC = "rpc"
+)
+
+// errReceiptNotFound is returned while a transaction is not mined.
+var errReceiptNotFound = errors.New("not found")
+
+// RPCError is an error calling a node. JSON-RPC errors carry the code,
+// message and data returned by the node, errors reaching it only a message.
+// It's the value of the errors thrown to JS, e.g. e.value.class.
+type RPCError struct {
+	Method  string
+	Code    int
+	Message string
+	Data    interface{}
+	Class   string
+	// Revert is the decoded revert data of execution_reverted errors
+	Revert *Revert
+
+	err error
+}
+
+func (e *RPCError) Error() string {
+	msg := e.Method + ": " + e.Message
+	if e.Revert != nil && !strings.Contains(e.Message, e.Revert.String()) {
+		msg += ": " + e.Revert.String()
+	}
+	return msg
+}
+
+func (e *RPCError) Unwrap() error {
+	return e.err
+}
+
+// newRPCError classifies err returned calling method, decoding the revert
+// data with the custom errors of contractABI if given.
+func newRPCError(method string, err error, contractABI *abi.ABI) *RPCError {
+	e := &RPCError{
+		Method:  method,
+		Message: err.Error(),
+		err:     err,
+	}
+
+	var obj *codec.ErrorObject
+	if !errors.As(err, &obj) {
+		e.Class = errClassTransport
+		if isMalformedResponse(err) {
+			e.Class = errClassRPC
+		}
+		if isTimeout(err) {
+			e.Class = errClassTimeout
+		}
+		return e
+	}
+
+	e.Code = obj.Code
+	e.Message = obj.Message
+	e.Data = obj.Data
+	e.Class = classifyMessage(obj.Code, obj.Message)
+	if e.Class == errClassExecutionReverted {
+		e.Revert = decodeRevert(obj.Data, contractABI)
+	}
+
+	return e
+}
+
+// classifyMessage returns the class of a JSON-RPC error from the messages
+// used by the most common clients.
+func classifyMessage(code int, message string) string {
+	msg := strings.ToLower(message)
+
+	switch {
+	case strings.Contains(msg, "nonce too low"):
+		return errClassNonceTooLow
+	case strings.Contains(msg, "nonce too high"):
+		return errClassNonceTooHigh
+	case strings.Contains(msg, "replacement transaction underpriced"),
+		strings.Contains(msg, "replacement fee too low"):
+		return errClassReplacementUnderpriced
+	case strings.Contains(msg, "underpriced"),
+		strings.Contains(msg, "less than block base fee"),
+		strings.Contains(msg, "fee too low"):
+		return errClassUnderpriced
+	case strings.Contains(msg, "insufficient funds"):
+		return errClassInsufficientFunds
+	case strings.Contains(msg, "already known"),
+		strings.Contains(msg, "alreadyknown"),
+		strings.Contains(msg, "known transaction"),
+		strings.Contains(msg, "already imported"):
+		return errClassAlreadyKnown
+	case code == 3, strings.Contains(msg, "revert"):
+		return errClassExecutionReverted
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "timed out"):
+		return errClassTimeout
+	default:
+		return errClassRPC
+	}
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "timeout") || strings.Contains(msg, "timed out")
+}
+
+// networkMessages are the messages of the errors reaching a node that don't
+// have a type to match, e.g. the ones of fasthttp.
+var networkMessages = []string{
+	"connection refused",
+	"connection reset",
+	"connection closed",
+	"closed connection",
+	"broken pipe",
+	"no such host",
+}
+
+// isNetworkError returns true if err was returned reaching a node, rather than
+// answered by it or returned before sending a request.
+func isNetworkError(err error) bool {
+	if errors.Is(err, errConnClosed) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, m := range networkMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+
+	return strings.HasSuffix(msg, "eof")
+}
+
+// isMalformedResponse returns true if err was returned decoding a response
+// that isn't JSON, e.g. the error page of a proxy. The node was reached, so
+// it's not retried.
+func isMalformedResponse(err error) bool {
+	var syntaxErr *json.SyntaxError
+	return errors.As(err, &syntaxErr)
+}
+
+// wrapError returns err as an RPCError and counts it in the ethereum_errors
+// metric. Nil errors are returned as is and errors wrapping an RPCError as
+// the RPCError, which was already counted. Errors that neither were answered
+// by the node nor reaching it, e.g. invalid arguments, are returned as is.
+func (c *Client) wrapError(method string, err error) error {
+	return c.wrapContractError(method, err, nil)
+}
+
+// wrapContractError is wrapError decoding reverts with the custom errors of contractABI.
+func (c *Client) wrapContractError(method string, err error, contractABI *abi.ABI) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *RPCError
+	if errors.As(err, &e) {
+		return e
+	}
+	var obj *codec.ErrorObject
+	if !errors.As(err, &obj) && !isTimeout(err) && !isNetworkError(err) && !isMalformedResponse(err) {
+		return err
+	}
+
+	e = newRPCError(method, err, contractABI)
+	c.reportError(e)
+
+	return e
+}
+
+func (c *Client) reportError(e *RPCError) {
+	// If we are testing vu is nil
+	if c.vu == nil || c.vu.State() == nil {
+		return
+	}
+
+	tags := map[string]string{
+		"method": e.Method,
+		"class":  e.Class,
+	}
+	if e.Revert != nil {
+		tags["revert"] = e.Revert.tag()
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), c.vu.State().Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.Errors,
+			Tags:   metrics.NewRegistry().RootTagSet().WithTagsFromMap(tags),
+		},
+		Value: 1,
+		Time:  time.Now(),
+	})
+}
@@ -0,0 +1,64 @@
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+)
+
+func Test_newRPCError(t *testing.T) {
+	tests := []struct {
+		err   error
+		class string
+	}{
+		{&codec.ErrorObject{Code: -32000, Message: "nonce too low"}, errClassNonceTooLow},
+		{&codec.ErrorObject{Code: -32000, Message: "nonce too high"}, errClassNonceTooHigh},
+		{&codec.ErrorObject{Code: -32000, Message: "replacement transaction underpriced"}, errClassReplacementUnderpriced},
+		{&codec.ErrorObject{Code: -32000, Message: "transaction underpriced"}, errClassUnderpriced},
+		{&codec.ErrorObject{Code: -32000, Message: "max fee per gas less than block base fee"}, errClassUnderpriced},
+		{&codec.ErrorObject{Code: -32000, Message: "insufficient funds for gas * price + value"}, errClassInsufficientFunds},
+		{&codec.ErrorObject{Code: -32000, Message: "already known"}, errClassAlreadyKnown},
+		{&codec.ErrorObject{Code: 3, Message: "execution reverted", Data: "0x08c379a0"}, errClassExecutionReverted},
+		{&codec.ErrorObject{Code: -32601, Message: "the method eth_foo does not exist"}, errClassRPC},
+		{fmt.Errorf("failed: %w", &codec.ErrorObject{Code: -32000, Message: "nonce too low"}), errClassNonceTooLow},
+		{errors.New("dial tcp 127.0.0.1:8545: connect: connection refused"), errClassTransport},
+		{errors.New("timeout"), errClassTimeout},
+	}
+
+	for _, tt := range tests {
+		e := newRPCError("eth_sendRawTransaction", tt.err, nil)
+		require.Equal(t, tt.class, e.Class, tt.err.Error())
+		require.Equal(t, "eth_sendRawTransaction", e.Method)
+	}
+
+	e := newRPCError("eth_call", &codec.ErrorObject{Code: -32000, Message: "execution reverted"}, nil)
+	require.Equal(t, -32000, e.Code)
+	require.Equal(t, "execution reverted", e.Message)
+	require.Nil(t, e.Revert)
+	require.Equal(t, "eth_call: execution reverted", e.Error())
+}
+
+func Test_wrapError(t *testing.T) {
+	c := &Client{}
+	require.NoError(t, c.wrapError("eth_call", nil))
+
+	err := c.wrapError("eth_estimateGas", &codec.ErrorObject{Code: -32000, Message: "insufficient funds"})
+	// errors already wrapped keep their method and class
+	err = c.wrapError("eth_sendRawTransaction", fmt.Errorf("failed: %w", err))
+
+	var e *RPCError
+	require.True(t, errors.As(err, &e))
+	require.Equal(t, "eth_estimateGas", e.Method)
+	require.Equal(t, errClassInsufficientFunds, e.Class)
+
+	// errors returned before sending a request aren't node errors
+	local := errors.New(`invalid wei amount "1.5"`)
+	require.Equal(t, local, c.wrapError("eth_sendRawTransaction", local))
+
+	err = c.wrapError("eth_blockNumber", errors.New("dial tcp 127.0.0.1:8545: connect: connection refused"))
+	require.True(t, errors.As(err, &e))
+	require.Equal(t, errClassTransport, e.Class)
+}
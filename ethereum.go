@@ -2,20 +2,26 @@
 package ethereum
 
 import (
+	"context"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/grafana/sobek"
 	"github.com/umbracle/ethgo"
 	"github.com/umbracle/ethgo/abi"
 	"github.com/umbracle/ethgo/contract"
-	"github.com/umbracle/ethgo/jsonrpc"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
 	"github.com/umbracle/ethgo/wallet"
 	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/lib/types"
 	"go.k6.io/k6/metrics"
 )
 
@@ -23,23 +29,126 @@ type Transaction struct {
 	From      string
 	To        string
 	Input     []byte
-	GasPrice  uint64
-	GasFeeCap uint64
-	GasTipCap uint64
+	GasPrice  Wei
+	GasFeeCap Wei
+	GasTipCap Wei
 	Gas       uint64
-	Value     int64
+	Value     Wei
 	Nonce     uint64
 	// eip-2930 values
-	ChainId int64
+	ChainId    int64
+	AccessList AccessList
+	// eip-4844 values, blobs are payloads of up to 126976 bytes or full
+	// 131072 bytes blobs, and blob count adds random blobs
+	MaxFeePerBlobGas Wei
+	Blobs            [][]byte
+	BlobCount        int
+
+	// calldata is the input built by the client, such as contract calls, sent
+	// as is instead of Input
+	calldata []byte
+}
+
+// to returns the recipient of the transaction, nil for contract creations.
+func (tx Transaction) to() *ethgo.Address {
+	if strings.TrimSpace(tx.To) == "" {
+		return nil
+	}
+
+	to := ethgo.HexToAddress(tx.To)
+	return &to
+}
+
+// data returns the transaction input, the calldata built by the client or
+// Input, given as bytes or as a 0x prefixed hex string which is decoded.
+func (tx Transaction) data() []byte {
+	if tx.calldata != nil {
+		return tx.calldata
+	}
+
+	return decodeHexBytes(tx.Input)
+}
+
+// decodeHexBytes returns the bytes encoded by b if it's a 0x prefixed hex
+// string, or b. Bytes without the prefix are never taken as hex.
+func decodeHexBytes(b []byte) []byte {
+	s := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(s, "0x") {
+		return b
+	}
+
+	d, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return b
+	}
+
+	return d
+}
+
+// setAmounts sets the value and fees of t, switching it to a dynamic fee
+// transaction when a fee cap or a tip cap is given, or to an access list
+// transaction when only an access list is given.
+func (tx Transaction) setAmounts(t *ethgo.Transaction) error {
+	value, err := tx.Value.Int()
+	if err != nil {
+		return err
+	}
+	t.Value = value
+	t.AccessList = tx.AccessList.toEthgo()
+
+	if !tx.GasFeeCap.IsZero() || !tx.GasTipCap.IsZero() {
+		feeCap, err := tx.GasFeeCap.Int()
+		if err != nil {
+			return err
+		}
+		tipCap, err := tx.GasTipCap.Int()
+		if err != nil {
+			return err
+		}
+		t.Type = ethgo.TransactionDynamicFee
+		t.GasPrice = 0
+		t.MaxFeePerGas = feeCap
+		t.MaxPriorityFeePerGas = tipCap
+		return nil
+	}
+
+	gasPrice, err := tx.GasPrice.Uint64()
+	if err != nil {
+		return err
+	}
+	t.GasPrice = gasPrice
+
+	if len(t.AccessList) > 0 {
+		t.Type = ethgo.TransactionAccessList
+	}
+
+	return nil
 }
 
 type Client struct {
-	w       *wallet.Key
-	client  *jsonrpc.Client
-	chainID *big.Int
-	vu      modules.VU
-	metrics ethMetrics
-	opts    *options
+	w         *wallet.Key
+	endpoints *endpointPool
+	chainID   *big.Int
+	vu        modules.VU
+	metrics   ethMetrics
+	opts      *options
+	monitor   *blockMonitor
+	// accounts sign raw transactions, w is the first one
+	accounts *accountPool
+	kzg      *kzgSetup
+	fees     *feeOracle
+
+	// replacements are the transactions replaced by SpeedUp and Cancel
+	replacements replacements
+
+	// ws is only set for ws:// and wss:// urls and carries subscriptions
+	ws       *wsConn
+	subsLock sync.Mutex
+	subs     map[string]*subscription
+
+	// propagation tracks the transactions sent by the client, set by
+	// PropagationTracker.Watch
+	propagation atomic.Pointer[PropagationTracker]
 }
 
 func (c *Client) Exports() modules.Exports {
@@ -47,191 +156,425 @@ func (c *Client) Exports() modules.Exports {
 }
 
 func (c *Client) Call(method string, params ...interface{}) (interface{}, error) {
-	t := time.Now()
-	var out interface{}
-	err := c.client.Call(method, &out, params...)
-	c.reportMetricsFromStats(method, time.Since(t))
-	return out, err
+	out, err := rpcCall(c, method, func(ctx context.Context, e *endpoint) (interface{}, error) {
+		var out interface{}
+		err := e.call(ctx, method, &out, params...)
+		return out, err
+	})
+	return out, c.wrapError(method, err)
 }
 
-func (c *Client) GasPrice() (uint64, error) {
-	t := time.Now()
-	g, err := c.client.Eth().GasPrice()
-	c.reportMetricsFromStats("gas_price", time.Since(t))
-	return g, err
+// GasPrice returns the current gas price in wei as a decimal string.
+func (c *Client) GasPrice() (string, error) {
+	g, err := rpcCall(c, "eth_gasPrice", func(ctx context.Context, e *endpoint) (uint64, error) {
+		return e.gasPrice(ctx)
+	})
+	return weiString(new(big.Int).SetUint64(g)), c.wrapError("eth_gasPrice", err)
 }
 
-func (c *Client) GetBalance(address string, blockNumber ethgo.BlockNumber) (uint64, error) {
-	b, err := c.client.Eth().GetBalance(ethgo.HexToAddress(address), blockNumber)
-	return b.Uint64(), err
+// GetBalance returns the balance in wei of the given address as a decimal string.
+func (c *Client) GetBalance(address string, blockNumber ethgo.BlockNumber) (string, error) {
+	b, err := rpcCall(c, "eth_getBalance", func(ctx context.Context, e *endpoint) (*big.Int, error) {
+		var b ethgo.ArgBig
+		err := e.call(ctx, "eth_getBalance", &b, ethgo.HexToAddress(address), blockNumber.Location())
+		return (*big.Int)(&b), err
+	})
+	if err != nil {
+		return "", c.wrapError("eth_getBalance", err)
+	}
+	return weiString(b), nil
 }
 
 // BlockNumber returns the current block number.
 func (c *Client) BlockNumber() (uint64, error) {
-	return c.client.Eth().BlockNumber()
+	n, err := rpcCall(c, "eth_blockNumber", func(ctx context.Context, e *endpoint) (uint64, error) {
+		return e.blockNumber(ctx)
+	})
+	return n, c.wrapError("eth_blockNumber", err)
 }
 
 // GetBlockByNumber returns the block with the given block number.
 func (c *Client) GetBlockByNumber(number ethgo.BlockNumber, full bool) (*ethgo.Block, error) {
-	return c.client.Eth().GetBlockByNumber(number, full)
+	b, err := rpcCall(c, "eth_getBlockByNumber", func(ctx context.Context, e *endpoint) (*ethgo.Block, error) {
+		return e.blockByNumber(ctx, number, full)
+	})
+	return b, c.wrapError("eth_getBlockByNumber", err)
 }
 
 // GetNonce returns the nonce for the given address.
 func (c *Client) GetNonce(address string) (uint64, error) {
-	return c.client.Eth().GetNonce(ethgo.HexToAddress(address), ethgo.Pending)
+	n, err := rpcCall(c, "eth_getTransactionCount", func(ctx context.Context, e *endpoint) (uint64, error) {
+		var n ethgo.ArgUint64
+		err := e.call(ctx, "eth_getTransactionCount", &n, ethgo.HexToAddress(address), ethgo.Pending.Location())
+		return n.Uint64(), err
+	})
+	return n, c.wrapError("eth_getTransactionCount", err)
 }
 
 // EstimateGas returns the estimated gas for the given transaction.
 func (c *Client) EstimateGas(tx Transaction) (uint64, error) {
-	to := ethgo.HexToAddress(tx.To)
+	gas, err := c.estimateGas(tx)
+	return gas, c.wrapError("eth_estimateGas", err)
+}
 
-	msg := &ethgo.CallMsg{
-		From:     c.w.Address(),
-		To:       &to,
-		Value:    big.NewInt(tx.Value),
-		Data:     tx.Input,
-		GasPrice: tx.GasPrice,
+func (c *Client) estimateGas(tx Transaction) (uint64, error) {
+	msg, err := c.callMsg(tx)
+	if err != nil {
+		return 0, err
 	}
 
-	gas, err := c.client.Eth().EstimateGas(msg)
+	out, err := rpcCall(c, "eth_estimateGas", func(ctx context.Context, e *endpoint) (string, error) {
+		var out string
+		err := e.call(ctx, "eth_estimateGas", &out, msg)
+		return out, err
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to estimate gas: %e", err)
+		return 0, err
 	}
 
-	return gas, nil
+	return strconv.ParseUint(out, 0, 64)
 }
 
-// SendTransaction sends a transaction to the network.
+// SendTransaction sends a transaction to the network. A transaction without
+// recipient creates a contract with input as init code.
 func (c *Client) SendTransaction(tx Transaction) (string, error) {
-	to := ethgo.HexToAddress(tx.To)
+	to := tx.to()
 
-	if tx.Gas == 0 {
+	// The node estimates the gas of contract creations
+	if tx.Gas == 0 && to != nil {
 		tx.Gas = 21000
 	}
 
-	if tx.GasPrice == 0 && tx.GasFeeCap == 0 && tx.GasTipCap == 0 {
-		tx.GasPrice = 5242880
+	if err := c.fillFees(&tx); err != nil {
+		return "", err
+	}
+	if tx.GasPrice.IsZero() && tx.GasFeeCap.IsZero() && tx.GasTipCap.IsZero() {
+		tx.GasPrice = "5242880"
 	}
 
 	t := &ethgo.Transaction{
-		Type:     ethgo.TransactionLegacy,
-		From:     ethgo.HexToAddress(tx.From),
-		To:       &to,
-		Value:    big.NewInt(tx.Value),
-		Gas:      tx.Gas,
-		GasPrice: tx.GasPrice,
+		Type:  ethgo.TransactionLegacy,
+		From:  ethgo.HexToAddress(tx.From),
+		To:    to,
+		Gas:   tx.Gas,
+		Input: tx.data(),
+	}
+	if err := tx.setAmounts(t); err != nil {
+		return "", err
 	}
 
-	if tx.GasFeeCap > 0 || tx.GasTipCap > 0 {
-		t.Type = ethgo.TransactionDynamicFee
-		t.GasPrice = 0
-		t.MaxFeePerGas = big.NewInt(0).SetUint64(tx.GasFeeCap)
-		t.MaxPriorityFeePerGas = big.NewInt(0).SetUint64(tx.GasTipCap)
+	sent := time.Now()
+	// not retried, the node would sign it again with another nonce
+	h, err := rpcCallOnce(c, "eth_sendTransaction", func(ctx context.Context, e *endpoint) (ethgo.Hash, error) {
+		var h ethgo.Hash
+		err := e.call(ctx, "eth_sendTransaction", &h, t)
+		return h, err
+	})
+	if err != nil {
+		return "", c.wrapError("eth_sendTransaction", err)
 	}
+	c.submitted(h, txTypeName(t.Type), sent)
 
-	h, err := c.client.Eth().SendTransaction(t)
-	return h.String(), err
+	return h.String(), nil
 }
 
-// SendRawTransaction signs and sends transaction to the network.
+// SendRawTransaction signs and sends transaction to the network with the
+// account given by from, an index or an address, or picked by the account
+// strategy. If the nonce manager is enabled and no nonce is given the managed
+// nonce of the account is used. A
+// transaction without recipient creates a contract with input as init code,
+// the address is found in the contract_address field of its receipt. A
+// transaction with blobs is sent as an eip-4844 transaction.
 func (c *Client) SendRawTransaction(tx Transaction) (string, error) {
-	to := ethgo.HexToAddress(tx.To)
-
-	gas, err := c.EstimateGas(tx)
+	acc, err := c.accounts.pick(tx.From)
 	if err != nil {
 		return "", err
 	}
+	tx.From = acc.key.Address().String()
+
+	if acc.nonces == nil || tx.Nonce != 0 {
+		return c.sendRawTransaction(acc.key, tx)
+	}
+
+	return acc.nonces.send(func(nonce uint64) (string, error) {
+		tx.Nonce = nonce
+		return c.sendRawTransaction(acc.key, tx)
+	})
+}
+
+func (c *Client) sendRawTransaction(key *wallet.Key, tx Transaction) (string, error) {
+	if err := c.fillFees(&tx); err != nil {
+		return "", err
+	}
+
+	gas := tx.Gas
+	if gas == 0 {
+		var err error
+		if gas, err = c.EstimateGas(tx); err != nil {
+			return "", err
+		}
+	}
+
+	if tx.isBlob() {
+		return c.sendBlobTransaction(key, tx, gas)
+	}
 
 	t := &ethgo.Transaction{
-		Type:     ethgo.TransactionLegacy,
-		From:     c.w.Address(),
-		To:       &to,
-		Value:    big.NewInt(tx.Value),
-		Gas:      gas,
-		GasPrice: tx.GasPrice,
-		Nonce:    tx.Nonce,
-		Input:    tx.Input,
-		ChainID:  c.chainID,
-	}
-
-	if tx.GasFeeCap > 0 || tx.GasTipCap > 0 {
-		t.Type = ethgo.TransactionDynamicFee
-		t.GasPrice = 0
-		t.MaxFeePerGas = big.NewInt(0).SetUint64(tx.GasFeeCap)
-		t.MaxPriorityFeePerGas = big.NewInt(0).SetUint64(tx.GasTipCap)
+		Type:    ethgo.TransactionLegacy,
+		From:    key.Address(),
+		To:      tx.to(),
+		Gas:     gas,
+		Nonce:   tx.Nonce,
+		Input:   tx.data(),
+		ChainID: c.chainID,
+	}
+	if err := tx.setAmounts(t); err != nil {
+		return "", err
 	}
 
 	s := wallet.NewEIP155Signer(t.ChainID.Uint64())
-	st, err := s.SignTx(t, c.w)
+	st, err := s.SignTx(t, key)
 	if err != nil {
 		return "", err
 	}
 
 	trlp, err := st.MarshalRLPTo(nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal tx: %e", err)
+		return "", fmt.Errorf("failed to marshal tx: %w", err)
 	}
 
-	h, err := c.client.Eth().SendRawTransaction(trlp)
-	return h.String(), err
+	sent := time.Now()
+	h, err := c.sendSigned(trlp, ethgo.BytesToHash(ethgo.Keccak256(trlp)))
+	if err != nil {
+		return "", err
+	}
+	c.submitted(h, txTypeName(t.Type), sent)
+
+	return h.String(), nil
 }
 
-// GetTransactionReceipt returns the transaction receipt for the given transaction hash.
-func (c *Client) GetTransactionReceipt(hash string) (*ethgo.Receipt, error) {
-	r, err := c.client.Eth().GetTransactionReceipt(ethgo.HexToHash(hash))
+// sendSigned sends raw, a signed transaction whose hash is hash. The same bytes
+// are sent on every retry, so a transaction already known by the node was sent
+// by an attempt that timed out and it's considered sent.
+func (c *Client) sendSigned(raw []byte, hash ethgo.Hash) (ethgo.Hash, error) {
+	h, err := rpcCall(c, "eth_sendRawTransaction", func(ctx context.Context, e *endpoint) (ethgo.Hash, error) {
+		var h ethgo.Hash
+		err := e.call(ctx, "eth_sendRawTransaction", &h, "0x"+hex.EncodeToString(raw))
+		var obj *codec.ErrorObject
+		if errors.As(err, &obj) && classifyMessage(obj.Code, obj.Message) == errClassAlreadyKnown {
+			return hash, nil
+		}
+		return h, err
+	})
 	if err != nil {
+		return ethgo.Hash{}, c.wrapError("eth_sendRawTransaction", err)
+	}
+
+	return h, nil
+}
+
+// Receipt is a transaction receipt with the fees paid, which ethgo doesn't
+// decode, as decimal strings.
+type Receipt struct {
+	*ethgo.Receipt
+	EffectiveGasPrice string
+	// BlobGasPrice is only set for blob transactions
+	BlobGasPrice string
+}
+
+// decodeReceipt decodes a raw eth_getTransactionReceipt result, nil while the
+// transaction is not mined.
+func decodeReceipt(raw json.RawMessage) (*Receipt, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	r := &Receipt{Receipt: new(ethgo.Receipt)}
+	if err := r.Receipt.UnmarshalJSON(raw); err != nil {
 		return nil, err
 	}
 
+	var fees struct {
+		EffectiveGasPrice *string `json:"effectiveGasPrice"`
+		BlobGasPrice      *string `json:"blobGasPrice"`
+	}
+	if err := json.Unmarshal(raw, &fees); err != nil {
+		return nil, err
+	}
+	if fees.EffectiveGasPrice != nil {
+		n, err := Wei(*fees.EffectiveGasPrice).Int()
+		if err != nil {
+			return nil, err
+		}
+		r.EffectiveGasPrice = weiString(n)
+	}
+	if fees.BlobGasPrice != nil {
+		n, err := Wei(*fees.BlobGasPrice).Int()
+		if err != nil {
+			return nil, err
+		}
+		r.BlobGasPrice = weiString(n)
+	}
+
+	return r, nil
+}
+
+// GetTransactionReceipt returns the transaction receipt for the given transaction hash.
+func (c *Client) GetTransactionReceipt(hash string) (*Receipt, error) {
+	r, err := rpcCall(c, "eth_getTransactionReceipt", func(ctx context.Context, e *endpoint) (*Receipt, error) {
+		var raw json.RawMessage
+		if err := e.call(ctx, "eth_getTransactionReceipt", &raw, ethgo.HexToHash(hash)); err != nil {
+			return nil, err
+		}
+		return decodeReceipt(raw)
+	})
+	if err != nil {
+		return nil, c.wrapError("eth_getTransactionReceipt", err)
+	}
+
 	if r != nil {
 		return r, nil
 	}
 
-	return nil, fmt.Errorf("not found")
+	return nil, errReceiptNotFound
+}
+
+// WaitOptions are the options of WaitForTransactionReceipt, durations are
+// strings like "30s" or milliseconds.
+type WaitOptions struct {
+	// Timeout rejects the promise if the transaction isn't mined in time, no timeout if not set.
+	Timeout interface{}
+	// PollInterval is the delay between receipt requests, defaults to 100ms.
+	PollInterval interface{} `js:"pollInterval"`
 }
 
 // WaitForTransactionReceipt waits for the transaction receipt for the given transaction hash.
-func (c *Client) WaitForTransactionReceipt(hash string) *sobek.Promise {
+func (c *Client) WaitForTransactionReceipt(hash string, opts WaitOptions) *sobek.Promise {
 	promise, resolve, reject := c.makeHandledPromise()
 	now := time.Now()
 
+	timeout, err := optionalDuration(opts.Timeout)
+	if err != nil {
+		reject(fmt.Errorf("invalid timeout: %w", err))
+		return promise
+	}
+	interval, err := optionalDuration(opts.PollInterval)
+	if err != nil {
+		reject(fmt.Errorf("invalid pollInterval: %w", err))
+		return promise
+	}
+
 	go func() {
-		for {
-			receipt, err := c.GetTransactionReceipt(hash)
-			if err != nil {
-				if err.Error() != "not found" {
-					reject(err)
-					return
-				}
-			}
-			if receipt != nil {
-				// If we are testing vu is nil
-				if c.vu != nil {
-					// Report metrics
-					metrics.PushIfNotDone(c.vu.Context(), c.vu.State().Samples, metrics.Sample{
-						TimeSeries: metrics.TimeSeries{
-							Metric: c.metrics.TimeToMine,
-							Tags:   metrics.NewRegistry().RootTagSet(),
-						},
-						Value: float64(time.Since(now) / time.Millisecond),
-						Time:  time.Now(),
-					})
-				}
-				resolve(receipt)
-				break
-			}
-			time.Sleep(100 * time.Millisecond)
+		receipt, err := c.waitForReceipt(c.vuContext(), hash, timeout, interval)
+		if err != nil {
+			reject(err)
+			return
 		}
+		c.reportTimeToMine(c.sinceSent(hash, now), nil)
+		resolve(receipt)
 	}()
 
 	return promise
 }
 
+const defaultPollInterval = 100 * time.Millisecond
+
+// waitForReceipt polls for the receipt of the given transaction hash until
+// it or one of its replacements is mined, until timeout if not zero, or until
+// ctx is done.
+func (c *Client) waitForReceipt(ctx context.Context, hash string, timeout, interval time.Duration) (*Receipt, error) {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		receipt, err := c.replacedReceipt(hash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, errReceiptNotFound) {
+			return nil, err
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-deadline:
+			c.reportTxTimeout()
+			err := fmt.Errorf("transaction %s not mined after %s: %w", hash, timeout, context.DeadlineExceeded)
+			return nil, c.wrapError("eth_getTransactionReceipt", err)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// vuContext returns the context of the VU of the client, or a background one
+// without it as in tests.
+func (c *Client) vuContext() context.Context {
+	if c.vu == nil {
+		return context.Background()
+	}
+
+	return c.vu.Context()
+}
+
+func (c *Client) reportTxTimeout() {
+	// If we are testing vu is nil
+	if c.vu == nil || c.vu.State() == nil {
+		return
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), c.vu.State().Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.TxTimeout,
+			Tags:   metrics.NewRegistry().RootTagSet(),
+		},
+		Value: 1,
+		Time:  time.Now(),
+	})
+}
+
+// optionalDuration parses a duration given as a string or milliseconds, zero if not set.
+func optionalDuration(v interface{}) (time.Duration, error) {
+	if v == nil {
+		return 0, nil
+	}
+
+	return types.GetDurationValue(v)
+}
+
+func (c *Client) reportTimeToMine(t time.Duration, tags map[string]string) {
+	// If we are testing vu is nil
+	if c.vu == nil || c.vu.State() == nil {
+		return
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), c.vu.State().Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.TimeToMine,
+			Tags:   metrics.NewRegistry().RootTagSet().WithTagsFromMap(tags),
+		},
+		Value: float64(t / time.Millisecond),
+		Time:  time.Now(),
+	})
+}
+
 // Accounts returns a list of addresses owned by client. This endpoint is not enabled in infrastructure providers.
 func (c *Client) Accounts() ([]string, error) {
-	accounts, err := c.client.Eth().Accounts()
+	accounts, err := rpcCall(c, "eth_accounts", func(ctx context.Context, e *endpoint) ([]ethgo.Address, error) {
+		var accounts []ethgo.Address
+		err := e.call(ctx, "eth_accounts", &accounts)
+		return accounts, err
+	})
 	if err != nil {
-		return nil, err
+		return nil, c.wrapError("eth_accounts", err)
 	}
 
 	addresses := make([]string, len(accounts))
@@ -250,7 +593,7 @@ func (c *Client) NewContract(address string, abistr string) (*Contract, error) {
 	}
 
 	opts := []contract.ContractOption{
-		contract.WithJsonRPC(c.client.Eth()),
+		contract.WithProvider(contractProvider{context.Background(), c.endpoints.primary()}),
 		contract.WithSender(c.w),
 	}
 
@@ -261,12 +604,13 @@ func (c *Client) NewContract(address string, abistr string) (*Contract, error) {
 
 	return &Contract{
 		Contract: contract,
+		address:  ethgo.HexToAddress(address),
 		client:   c,
 	}, nil
 }
 
 // DeployContract deploys a contract to the blockchain.
-func (c *Client) DeployContract(abistr string, bytecode string, args ...interface{}) (*ethgo.Receipt, error) {
+func (c *Client) DeployContract(abistr string, bytecode string, args ...interface{}) (*Receipt, error) {
 	// Parse ABI
 	contractABI, err := abi.NewABI(abistr)
 	if err != nil {
@@ -279,31 +623,25 @@ func (c *Client) DeployContract(abistr string, bytecode string, args ...interfac
 		return nil, fmt.Errorf("failed to decode bytecode: %w", err)
 	}
 
-	opts := []contract.ContractOption{
-		contract.WithJsonRPC(c.client.Eth()),
-		contract.WithSender(c.w),
-	}
-
-	// Deploy contract
-	txn, err := contract.DeployContract(contractABI, contractBytecode, args, opts...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to deploy contract: %w", err)
-	}
-	txn.WithOpts(&contract.TxnOpts{
-		GasLimit: 1500000,
-	})
-
-	err = txn.Do()
-	if err != nil {
-		return nil, fmt.Errorf("failed to deploy contract: %w", err)
+	// constructor arguments are appended to the bytecode
+	input := contractBytecode
+	if contractABI.Constructor != nil {
+		data, err := abi.Encode(args, contractABI.Constructor.Inputs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deploy contract: %w", err)
+		}
+		input = append(input, data...)
 	}
 
-	receipt, err := txn.Wait()
+	hash, err := c.sendContractTxn(Transaction{
+		Gas:      1500000,
+		calldata: input,
+	}, contractABI)
 	if err != nil {
-		return nil, fmt.Errorf("failed waiting to deploy contract: %w", err)
+		return nil, err
 	}
 
-	return receipt, nil
+	return c.waitForReceipt(c.vuContext(), hash, 0, 0)
 }
 
 // makeHandledPromise will create a promise and return its resolve and reject methods,
@@ -328,101 +666,3 @@ func (c *Client) makeHandledPromise() (*sobek.Promise, func(interface{}), func(i
 			})
 		}
 }
-
-var blocks sync.Map
-
-// PollBlocks polls for new blocks and emits a "block" metric.
-func (c *Client) pollForBlocks() {
-	var lastBlockNumber uint64
-	var prevBlock *ethgo.Block
-
-	now := time.Now()
-
-	for range time.Tick(500 * time.Millisecond) {
-		blockNumber, err := c.BlockNumber()
-		if err != nil {
-			panic(err)
-		}
-
-		if blockNumber > lastBlockNumber {
-			// compute precise block time
-			blockTime := time.Since(now)
-			now = time.Now()
-
-			block, err := c.GetBlockByNumber(ethgo.BlockNumber(blockNumber), false)
-			if err != nil {
-				panic(err)
-			}
-			if block == nil {
-				// We're not going to continue past this point if we don't have a block
-				continue
-			}
-			lastBlockNumber = blockNumber
-
-			var blockTimestampDiff time.Duration
-			var tps float64
-
-			if prevBlock != nil {
-				// compute block time
-				blockTimestampDiff = time.Unix(int64(block.Timestamp), 0).Sub(time.Unix(int64(prevBlock.Timestamp), 0))
-				// Compute TPS
-				tps = float64(len(block.TransactionsHashes)) / float64(blockTimestampDiff.Seconds())
-			}
-
-			prevBlock = block
-
-			rootTS := metrics.NewRegistry().RootTagSet()
-			if c.vu != nil || c.vu.State() != nil || rootTS != nil {
-				if _, loaded := blocks.LoadOrStore(c.opts.URL+strconv.FormatUint(blockNumber, 10), true); loaded {
-					// We already have a block number for this client, so we can skip this
-					continue
-				}
-
-				metrics.PushIfNotDone(c.vu.Context(), c.vu.State().Samples, metrics.ConnectedSamples{
-					Samples: []metrics.Sample{
-						{
-							TimeSeries: metrics.TimeSeries{
-								Metric: c.metrics.Block,
-								Tags: rootTS.WithTagsFromMap(map[string]string{
-									"transactions": strconv.Itoa(len(block.TransactionsHashes)),
-									"gas_used":     strconv.Itoa(int(block.GasUsed)),
-									"gas_limit":    strconv.Itoa(int(block.GasLimit)),
-								}),
-							},
-							Value: float64(blockNumber),
-							Time:  time.Now(),
-						},
-						{
-							TimeSeries: metrics.TimeSeries{
-								Metric: c.metrics.GasUsed,
-								Tags: rootTS.WithTagsFromMap(map[string]string{
-									"block": strconv.Itoa(int(blockNumber)),
-								}),
-							},
-							Value: float64(block.GasUsed),
-							Time:  time.Now(),
-						},
-						{
-							TimeSeries: metrics.TimeSeries{
-								Metric: c.metrics.TPS,
-								Tags:   rootTS,
-							},
-							Value: tps,
-							Time:  time.Now(),
-						},
-						{
-							TimeSeries: metrics.TimeSeries{
-								Metric: c.metrics.BlockTime,
-								Tags: rootTS.WithTagsFromMap(map[string]string{
-									"block_timestamp_diff": blockTimestampDiff.String(),
-								}),
-							},
-							Value: float64(blockTime.Milliseconds()),
-							Time:  time.Now(),
-						},
-					},
-				})
-			}
-		}
-	}
-}
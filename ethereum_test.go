@@ -3,8 +3,10 @@ package ethereum
 import (
 	"encoding/hex"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
 	"github.com/umbracle/ethgo/jsonrpc"
 	"github.com/umbracle/ethgo/wallet"
 )
@@ -20,10 +22,16 @@ func setupClient() (*Client, error) {
 		return nil, err
 	}
 
+	endpoints, err := newEndpointPool(strategyRoundRobin, []*endpoint{{url: url, client: c}})
+	if err != nil {
+		return nil, err
+	}
+
 	return &Client{
-		client:  c,
-		w:       wa,
-		chainID: cid,
+		endpoints: endpoints,
+		w:         wa,
+		accounts:  &accountPool{accounts: []*account{{key: wa}}},
+		chainID:   cid,
 	}, nil
 }
 
@@ -60,17 +68,42 @@ func Test_SendRawTransaction(t *testing.T) {
 	// Deploy the contract
 	tx, err := client.SendRawTransaction(Transaction{
 		To:    "0xDEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF",
-		Value: 1000000000000000000,
+		Value: "1000000000000000000",
 		Nonce: nonce,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	prom := client.WaitForTransactionReceipt(tx)
+	prom := client.WaitForTransactionReceipt(tx, WaitOptions{})
 	t.Log(prom)
 }
 
+func Test_SendRawTransactionContractCreation(t *testing.T) {
+	client, err := setupClient()
+	require.NoError(t, err)
+
+	nonce, err := client.GetNonce(client.w.Address().String())
+	require.NoError(t, err)
+
+	gasPrice, err := client.GasPrice()
+	require.NoError(t, err)
+
+	txh, err := client.SendRawTransaction(Transaction{
+		Input:    []byte("0x6080604052348015600f57600080fd5b50603f80601d6000396000f3fe6080604052600080fdfea164736f6c6343000806000a"),
+		GasPrice: Wei(gasPrice),
+		Nonce:    nonce,
+	})
+	require.NoError(t, err)
+
+	var receipt *Receipt
+	for receipt == nil {
+		receipt, _ = client.GetTransactionReceipt(txh)
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.NotEqual(t, ethgo.ZeroAddress, receipt.ContractAddress)
+}
+
 func Test_DeployContract(t *testing.T) {
 	client, err := setupClient()
 	require.NoError(t, err)
@@ -96,9 +129,36 @@ func Test_DeployContract(t *testing.T) {
 	t.Log(res)
 }
 
-func Test_pollForBlocks(t *testing.T) {
+func Test_decodeReceipt(t *testing.T) {
+	r, err := decodeReceipt([]byte(testReceipt(ethgo.ZeroAddress, ethgo.Hash{1},
+		`"effectiveGasPrice":"0x3b9aca00"`, `"blobGasPrice":"0x1"`)))
+	require.NoError(t, err)
+	require.Equal(t, uint64(21000), r.GasUsed)
+	require.Equal(t, "1000000000", r.EffectiveGasPrice)
+	require.Equal(t, "1", r.BlobGasPrice)
+
+	r, err = decodeReceipt([]byte("null"))
+	require.NoError(t, err)
+	require.Nil(t, r)
+}
+
+func Test_blockMonitor(t *testing.T) {
 	client, err := setupClient()
 	require.NoError(t, err)
 
-	client.pollForBlocks()
+	bm := newBlockMonitor(nil, ethMetrics{}, client.endpoints.primary(), monitorConfig{})
+	defer bm.stop()
+
+	require.NoError(t, bm.poll())
+	require.NotZero(t, bm.lastBlockNumber)
+}
+
+func Test_TransactionData(t *testing.T) {
+	// only 0x prefixed strings are decoded
+	require.Equal(t, []byte{0xab}, Transaction{Input: []byte("0xab")}.data())
+	require.Equal(t, []byte("ab"), Transaction{Input: []byte("ab")}.data())
+	require.Equal(t, []byte{0xab, 0xcd}, Transaction{Input: []byte{0xab, 0xcd}}.data())
+
+	// calldata built by the client is never decoded
+	require.Equal(t, []byte("0xab"), Transaction{Input: []byte("0x01"), calldata: []byte("0xab")}.data())
 }
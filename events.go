@@ -0,0 +1,253 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/abi"
+)
+
+// Event is a contract event log decoded with the contract ABI.
+type Event struct {
+	Name string
+	// Args are the event arguments by name, uint and int values are decimal
+	// strings and addresses hex strings
+	Args map[string]interface{}
+	Log  *ethgo.Log
+}
+
+// EventFilter selects the logs returned by GetEvents.
+type EventFilter struct {
+	// FromBlock and ToBlock are block numbers or latest, earliest or pending
+	FromBlock string `js:"fromBlock"`
+	ToBlock   string `js:"toBlock"`
+	// Filters are the values of indexed arguments by name, an array matches
+	// any of its values
+	Filters map[string]interface{}
+}
+
+// DecodeLogs returns the events emitted by the contract in a transaction
+// receipt, as returned by getTransactionReceipt or txnAsync. Logs of other
+// contracts or unknown events are skipped, and so are logs matching an event
+// signature but not its arguments, e.g. an ERC-721 Transfer decoded as an
+// ERC-20 one.
+func (c *Contract) DecodeLogs(receipt interface{}) ([]*Event, error) {
+	logs, ok := receiptLogs(receipt)
+	if !ok {
+		return nil, fmt.Errorf("receipt is required")
+	}
+
+	events := []*Event{}
+	for _, log := range logs {
+		if log.Address != c.address {
+			continue
+		}
+
+		event, err := c.decodeLog(log)
+		if err != nil {
+			continue
+		}
+		if event != nil {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// receiptLogs returns the logs of any of the receipt types, false if receipt
+// isn't one.
+func receiptLogs(receipt interface{}) ([]*ethgo.Log, bool) {
+	switch r := receipt.(type) {
+	case *ethgo.Receipt:
+		if r != nil {
+			return r.Logs, true
+		}
+	case *Receipt:
+		if r != nil && r.Receipt != nil {
+			return r.Logs, true
+		}
+	case *TxnReceipt:
+		if r != nil && r.Receipt != nil && r.Receipt.Receipt != nil {
+			return r.Logs, true
+		}
+	}
+
+	return nil, false
+}
+
+// GetEvents returns the events of the contract with the given name matching filter.
+func (c *Contract) GetEvents(eventName string, filter EventFilter) ([]*Event, error) {
+	event, ok := c.GetABI().Events[eventName]
+	if !ok {
+		return nil, fmt.Errorf("event %s not found", eventName)
+	}
+
+	topics, err := eventTopics(event, filter.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	lf := &ethgo.LogFilter{
+		Address: []ethgo.Address{c.address},
+		Topics:  topics,
+	}
+	if filter.FromBlock != "" {
+		from, err := parseBlockNumber(filter.FromBlock)
+		if err != nil {
+			return nil, err
+		}
+		lf.From = &from
+	}
+	if filter.ToBlock != "" {
+		to, err := parseBlockNumber(filter.ToBlock)
+		if err != nil {
+			return nil, err
+		}
+		lf.To = &to
+	}
+
+	logs, err := rpcCall(c.client, "eth_getLogs", func(ctx context.Context, e *endpoint) ([]*ethgo.Log, error) {
+		var logs []*ethgo.Log
+		err := e.call(ctx, "eth_getLogs", &logs, lf)
+		return logs, err
+	})
+	if err != nil {
+		return nil, c.client.wrapError("eth_getLogs", err)
+	}
+
+	events := make([]*Event, 0, len(logs))
+	for _, log := range logs {
+		args, err := event.ParseLog(log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s log: %w", eventName, err)
+		}
+		events = append(events, &Event{Name: event.Name, Args: eventArgs(args), Log: log})
+	}
+
+	return events, nil
+}
+
+// decodeLog decodes log with the matching event of the ABI, nil if none matches.
+func (c *Contract) decodeLog(log *ethgo.Log) (*Event, error) {
+	for _, event := range c.GetABI().Events {
+		if !event.Match(log) {
+			continue
+		}
+
+		args, err := event.ParseLog(log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s log: %w", event.Name, err)
+		}
+
+		return &Event{Name: event.Name, Args: eventArgs(args), Log: log}, nil
+	}
+
+	return nil, nil
+}
+
+// eventTopics returns the topics matching event with the indexed argument
+// values in filters.
+func eventTopics(event *abi.Event, filters map[string]interface{}) ([][]*ethgo.Hash, error) {
+	id := event.ID()
+	topics := [][]*ethgo.Hash{{&id}}
+
+	for _, arg := range event.Inputs.TupleElems() {
+		if !arg.Indexed {
+			continue
+		}
+
+		value, ok := filters[arg.Name]
+		if !ok || value == nil {
+			topics = append(topics, nil)
+			continue
+		}
+
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+
+		hashes := make([]*ethgo.Hash, len(values))
+		for i, v := range values {
+			h, err := encodeTopic(arg.Elem, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter %s: %w", arg.Name, err)
+			}
+			hashes[i] = &h
+		}
+		topics = append(topics, hashes)
+	}
+
+	for name := range filters {
+		if !isIndexedArg(event, name) {
+			return nil, fmt.Errorf("%s is not an indexed argument of %s", name, event.Name)
+		}
+	}
+
+	// trailing wildcards are not needed
+	for len(topics) > 1 && topics[len(topics)-1] == nil {
+		topics = topics[:len(topics)-1]
+	}
+
+	return topics, nil
+}
+
+// encodeTopic encodes v as a topic of type t. Values abi can't encode, like
+// fixed bytes or hashes of dynamic types, are taken as 32 bytes hex strings.
+func encodeTopic(t *abi.Type, v interface{}) (ethgo.Hash, error) {
+	h, err := abi.EncodeTopic(t, v)
+	if err == nil {
+		return h, nil
+	}
+
+	if s, ok := v.(string); ok && len(strings.TrimPrefix(s, "0x")) == 64 {
+		return ethgo.HexToHash(s), nil
+	}
+
+	return ethgo.Hash{}, err
+}
+
+func isIndexedArg(event *abi.Event, name string) bool {
+	for _, arg := range event.Inputs.TupleElems() {
+		if arg.Indexed && arg.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// eventArgs converts the decoded event arguments to JS friendly values.
+func eventArgs(args map[string]interface{}) map[string]interface{} {
+	for k, v := range args {
+		if addr, ok := v.(ethgo.Address); ok {
+			args[k] = addr.String()
+			continue
+		}
+		args[k] = bigIntsToStrings(v)
+	}
+
+	return args
+}
+
+// parseBlockNumber parses a block number or tag.
+func parseBlockNumber(s string) (ethgo.BlockNumber, error) {
+	switch s = strings.TrimSpace(s); s {
+	case "latest":
+		return ethgo.Latest, nil
+	case "earliest":
+		return ethgo.Earliest, nil
+	case "pending":
+		return ethgo.Pending, nil
+	}
+
+	n, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block number %q", s)
+	}
+
+	return ethgo.BlockNumber(n), nil
+}
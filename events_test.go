@@ -0,0 +1,67 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/abi"
+	"github.com/umbracle/ethgo/contract"
+)
+
+func Test_DecodeLogs(t *testing.T) {
+	erc20, err := abi.NewABIFromList([]string{
+		"event Transfer(address indexed from, address indexed to, uint256 value)",
+	})
+	require.NoError(t, err)
+
+	address := ethgo.HexToAddress("0x1000000000000000000000000000000000000001")
+	c := &Contract{
+		Contract: contract.NewContract(address, erc20),
+		address:  address,
+	}
+
+	from := ethgo.HexToAddress("0x85da99c8a7c2c95964c8efd687e95e632fc533d6")
+	to := ethgo.HexToAddress("0x2000000000000000000000000000000000000002")
+	transfer := erc20.Events["Transfer"]
+
+	topics, err := eventTopics(transfer, map[string]interface{}{"to": to.String()})
+	require.NoError(t, err)
+	require.Len(t, topics, 3)
+	require.Nil(t, topics[1])
+
+	data, err := abi.Encode(map[string]interface{}{"value": big.NewInt(100)}, abi.MustNewType("tuple(uint256 value)"))
+	require.NoError(t, err)
+
+	log := &ethgo.Log{
+		Address: address,
+		Topics:  []ethgo.Hash{*topics[0][0], ethgo.BytesToHash(from.Bytes()), *topics[2][0]},
+		Data:    data,
+	}
+	other := &ethgo.Log{Address: to, Topics: log.Topics, Data: data}
+
+	// a log with the Transfer signature but another indexed arguments layout
+	erc721 := &ethgo.Log{Address: address, Topics: log.Topics[:1], Data: data}
+
+	receipt := &Receipt{Receipt: &ethgo.Receipt{Logs: []*ethgo.Log{other, erc721, log}}}
+	for _, r := range []interface{}{receipt.Receipt, receipt, &TxnReceipt{Receipt: receipt}} {
+		events, err := c.DecodeLogs(r)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+	}
+	_, err = c.DecodeLogs(nil)
+	require.Error(t, err)
+
+	events, err := c.DecodeLogs(receipt)
+	require.NoError(t, err)
+	require.Equal(t, "Transfer", events[0].Name)
+	require.Equal(t, map[string]interface{}{
+		"from":  from.String(),
+		"to":    to.String(),
+		"value": "100",
+	}, events[0].Args)
+
+	_, err = eventTopics(transfer, map[string]interface{}{"value": 1})
+	require.Error(t, err)
+}
@@ -0,0 +1,36 @@
+import eth from 'k6/x/ethereum';
+
+let rpc_url = __ENV.RCP_URL
+if (rpc_url == undefined) {
+  rpc_url = "http://localhost:10002"
+}
+
+const root_address = "0x85da99c8a7c2c95964c8efd687e95e632fc533d6"
+// the same trusted setup used by the node, e.g. c-kzg's trusted_setup.txt
+const trustedSetup = open('./trusted_setup.txt');
+
+export const options = {
+  vus: 1,
+  iterations: 10,
+};
+
+export default function () {
+  const client = new eth.Client({
+    url: rpc_url,
+    nonceManager: true,
+    trustedSetup: trustedSetup,
+  });
+
+  const txh = client.sendRawTransaction({
+    to: root_address,
+    gas_fee_cap: "20000000000",
+    gas_tip_cap: "1000000000",
+    blobs: ["hello blobs"],
+    blob_count: 2,
+  });
+  console.log(`blob tx hash => ${txh}`);
+
+  client.waitForTransactionReceipt(txh).then((receipt) => {
+    console.log(`blob tx block => ${receipt.block_number}`);
+  });
+}
@@ -1,6 +1,6 @@
 import eth from 'k6/x/ethereum';
 import exec from 'k6/execution';
-import { fundTestAccounts } from '../helpers/init.js';
+import { fundTestAccounts, sweepTestAccounts } from '../helpers/init.js';
 import { textSummary } from 'https://jslib.k6.io/k6-summary/0.0.2/index.js';
 
 // export const options = {
@@ -33,7 +33,9 @@ export function setup() {
   return {accounts: fundTestAccounts(root_address, url)};
 }
 
-var nonce = 0;
+export function teardown(data) {
+  console.log(`swept => ${sweepTestAccounts(data.accounts, url)}`);
+}
 
 var client;
 
@@ -42,22 +44,21 @@ export default function (data) {
   if (client == null) {
     client = new eth.Client({
       url: url,
-      privateKey: data.accounts[exec.vu.idInInstance - 1].private_key
+      privateKey: data.accounts[exec.vu.idInInstance - 1].private_key,
+      nonceManager: true,
     });
   }
 
-  console.log(`nonce => ${nonce}`);
-  
+  console.log(`nonce => ${client.nonce()}`);
+
   const tx = {
     to: "0xDEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF",
-    value: Number(0.0001 * 1e18),
+    value: "100000000000000",
     gas_price: client.gasPrice(),
-    nonce: nonce,
   };
 
   const txh = client.sendRawTransaction(tx);
   console.log("tx hash => " + txh);
-  nonce++;
 
   // client.waitForTransactionReceipt(txh).then((receipt) => {
   //   console.log("tx block hash => " + receipt.block_hash);
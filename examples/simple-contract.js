@@ -26,8 +26,9 @@ export default function (data) {
   console.log(JSON.stringify(data));
 
   const con = client.newContract(data.contract_address, contract_abi);
-  const res = con.txn("burnGas", 10);
-  console.log(`txn hash => ${res.transaction_hash}`);
-  console.log(`gas used => ${res.gas_used}`);
-  console.log(JSON.stringify(con.call("getTotal")));
+  con.txnAsync("burnGas", {}, 10).then((res) => {
+    console.log(`txn hash => ${res.transaction_hash}`);
+    console.log(`gas used => ${res.gas_used}`);
+    console.log(JSON.stringify(con.call("getTotal")));
+  });
 }
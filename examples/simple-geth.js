@@ -14,7 +14,7 @@ export function setup() {
       const txh = client.sendTransaction({
         from: accounts[0],
         to: root_address,
-        value: Number(1000 * 1e18),
+        value: "1000000000000000000000",
       });
       const rcp = client.waitForTransactionReceipt(txh)
     }
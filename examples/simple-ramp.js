@@ -44,7 +44,7 @@ export default function (data) {
   
   const tx = {
     to: "0xDEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF",
-    value: Number(0.001 * 1e18),
+    value: "1000000000000000",
     gas_price: gas,
     nonce: data.nonce,
   };
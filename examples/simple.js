@@ -32,7 +32,7 @@ export default function (data) {
   
   const tx = {
     to: "0xDEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF",
-    value: Number(0.0001 * 1e18),
+    value: "100000000000000",
     gas_price: gas,
     nonce: nonce,
   };
@@ -0,0 +1,20 @@
+import eth from 'k6/x/ethereum';
+
+const client = new eth.Client({url: "ws://localhost:8546"});
+
+export const options = {
+  vus: 1,
+  iterations: 1,
+};
+
+export default function () {
+  let heads = 0;
+
+  const id = client.subscribe("newHeads", null, (head) => {
+    console.log(`new head => ${head.number} ${head.hash}`);
+    heads++;
+    if (heads == 10) {
+      client.unsubscribe(id);
+    }
+  });
+}
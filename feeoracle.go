@@ -0,0 +1,247 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/jsonrpc"
+)
+
+// Fee strategies of the fee oracle.
+const (
+	feeStrategyLegacy  = "legacy"
+	feeStrategyEIP1559 = "eip1559"
+	feeStrategyFixed   = "fixed"
+)
+
+const (
+	defaultBaseFeeMultiplier = 2.0
+	// feeHistoryBlocks is the number of blocks the median priority fee is
+	// taken from when the node doesn't support eth_maxPriorityFeePerGas.
+	feeHistoryBlocks = 5
+	// feeOracleTTL is how long fees are cached without a block monitor to
+	// tell when a new block is mined.
+	feeOracleTTL = time.Second
+)
+
+// feeOptions configures the fees filled in transactions that don't set any.
+type feeOptions struct {
+	// Strategy is one of legacy, using eth_gasPrice, eip1559, using the base
+	// fee and the priority fee suggested by the node, or fixed.
+	Strategy string `json:"strategy"`
+	// BaseFeeMultiplier is applied to the base fee of the next block to
+	// compute the fee cap of eip1559 fees, defaults to 2.
+	BaseFeeMultiplier float64 `json:"baseFeeMultiplier,omitempty"`
+	// GasPrice, MaxFeePerGas and MaxPriorityFeePerGas are the fixed fees,
+	// either the gas price or the fee caps.
+	GasPrice             Wei `json:"gasPrice,omitempty"`
+	MaxFeePerGas         Wei `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas Wei `json:"maxPriorityFeePerGas,omitempty"`
+}
+
+func (o *feeOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+
+	switch o.Strategy {
+	case feeStrategyLegacy, feeStrategyEIP1559:
+	case feeStrategyFixed:
+		if o.GasPrice.IsZero() && o.MaxFeePerGas.IsZero() && o.MaxPriorityFeePerGas.IsZero() {
+			return errors.New("fixed fees require gasPrice or maxFeePerGas and maxPriorityFeePerGas")
+		}
+		if !o.GasPrice.IsZero() && (!o.MaxFeePerGas.IsZero() || !o.MaxPriorityFeePerGas.IsZero()) {
+			return errors.New("fixed fees can't set both gasPrice and fee caps")
+		}
+	default:
+		return fmt.Errorf("unknown fee strategy %s", o.Strategy)
+	}
+
+	if o.BaseFeeMultiplier < 0 {
+		return errors.New("baseFeeMultiplier can't be negative")
+	}
+	for _, w := range []Wei{o.GasPrice, o.MaxFeePerGas, o.MaxPriorityFeePerGas} {
+		if _, err := w.Int(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fees are either a gas price or the fee caps of a dynamic fee transaction.
+type fees struct {
+	gasPrice *big.Int
+	feeCap   *big.Int
+	tipCap   *big.Int
+}
+
+// feeOracle suggests the fees of the transactions sent by a client, cached
+// until a new block is mined.
+type feeOracle struct {
+	client *Client
+	opts   feeOptions
+
+	lock    sync.Mutex
+	cached  *fees
+	block   uint64
+	updated time.Time
+	// noMaxPriorityFee is set once the node fails eth_maxPriorityFeePerGas
+	noMaxPriorityFee bool
+}
+
+func newFeeOracle(c *Client, opts feeOptions) *feeOracle {
+	if opts.BaseFeeMultiplier == 0 {
+		opts.BaseFeeMultiplier = defaultBaseFeeMultiplier
+	}
+
+	return &feeOracle{client: c, opts: opts}
+}
+
+// fees returns the suggested fees, refreshed when the block monitor of the
+// client sees a new block or after feeOracleTTL without one.
+func (o *feeOracle) fees() (fees, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	var head uint64
+	if o.client.monitor != nil {
+		head = o.client.monitor.head()
+	}
+
+	if o.cached != nil {
+		if head > 0 && head == o.block {
+			return *o.cached, nil
+		}
+		if head == 0 && time.Since(o.updated) < feeOracleTTL {
+			return *o.cached, nil
+		}
+	}
+
+	f, err := o.fetch()
+	if err != nil {
+		return fees{}, err
+	}
+	o.cached = &f
+	o.block = head
+	o.updated = time.Now()
+
+	return f, nil
+}
+
+func (o *feeOracle) fetch() (fees, error) {
+	c := o.client
+
+	switch o.opts.Strategy {
+	case feeStrategyFixed:
+		if !o.opts.GasPrice.IsZero() {
+			gasPrice, err := o.opts.GasPrice.Int()
+			return fees{gasPrice: gasPrice}, err
+		}
+		feeCap, err := o.opts.MaxFeePerGas.Int()
+		if err != nil {
+			return fees{}, err
+		}
+		tipCap, err := o.opts.MaxPriorityFeePerGas.Int()
+		return fees{feeCap: feeCap, tipCap: tipCap}, err
+
+	case feeStrategyEIP1559:
+		history, err := rpcCall(c, "eth_feeHistory", func(ctx context.Context, e *endpoint) (*jsonrpc.FeeHistory, error) {
+			var out *jsonrpc.FeeHistory
+			err := e.call(ctx, "eth_feeHistory", &out, ethgo.BlockNumber(feeHistoryBlocks).String(), ethgo.Latest.String(), []float64{50})
+			return out, err
+		})
+		if err != nil {
+			return fees{}, c.wrapError("eth_feeHistory", err)
+		}
+		if history == nil || len(history.BaseFee) == 0 {
+			return fees{}, errors.New("eth_feeHistory returned no base fee, the chain may not support eip-1559")
+		}
+		// the last base fee is the one of the next block
+		baseFee := history.BaseFee[len(history.BaseFee)-1]
+
+		tipCap, err := o.priorityFee(history)
+		if err != nil {
+			return fees{}, err
+		}
+
+		feeCap, _ := new(big.Float).Mul(new(big.Float).SetInt(baseFee), big.NewFloat(o.opts.BaseFeeMultiplier)).Int(nil)
+		feeCap.Add(feeCap, tipCap)
+
+		return fees{feeCap: feeCap, tipCap: tipCap}, nil
+
+	default:
+		gasPrice, err := rpcCall(c, "eth_gasPrice", func(ctx context.Context, e *endpoint) (uint64, error) {
+			return e.gasPrice(ctx)
+		})
+		if err != nil {
+			return fees{}, c.wrapError("eth_gasPrice", err)
+		}
+		return fees{gasPrice: new(big.Int).SetUint64(gasPrice)}, nil
+	}
+}
+
+// priorityFee returns the priority fee suggested by eth_maxPriorityFeePerGas,
+// or the median of the ones paid in the recent blocks if it's not supported.
+func (o *feeOracle) priorityFee(history *jsonrpc.FeeHistory) (*big.Int, error) {
+	c := o.client
+
+	if !o.noMaxPriorityFee {
+		out, err := rpcCall(c, "eth_maxPriorityFeePerGas", func(ctx context.Context, e *endpoint) (string, error) {
+			var out string
+			err := e.call(ctx, "eth_maxPriorityFeePerGas", &out)
+			return out, err
+		})
+		if err == nil {
+			return Wei(out).Int()
+		}
+		if isRetryable(err) {
+			return nil, c.wrapError("eth_maxPriorityFeePerGas", err)
+		}
+		o.noMaxPriorityFee = true
+	}
+
+	var rewards []*big.Int
+	for _, r := range history.Reward {
+		if len(r) > 0 && r[0] != nil {
+			rewards = append(rewards, r[0])
+		}
+	}
+	if len(rewards) == 0 {
+		return new(big.Int), nil
+	}
+
+	sort.Slice(rewards, func(i, j int) bool {
+		return rewards[i].Cmp(rewards[j]) < 0
+	})
+
+	return rewards[len(rewards)/2], nil
+}
+
+// fillFees sets the fees suggested by the fee oracle in tx if it doesn't set
+// any, nothing if the client has no fee oracle.
+func (c *Client) fillFees(tx *Transaction) error {
+	if c.fees == nil || !tx.GasPrice.IsZero() || !tx.GasFeeCap.IsZero() || !tx.GasTipCap.IsZero() {
+		return nil
+	}
+
+	f, err := c.fees.fees()
+	if err != nil {
+		return err
+	}
+
+	if f.gasPrice != nil {
+		tx.GasPrice = Wei(weiString(f.gasPrice))
+		return nil
+	}
+	tx.GasFeeCap = Wei(weiString(f.feeCap))
+	tx.GasTipCap = Wei(weiString(f.tipCap))
+
+	return nil
+}
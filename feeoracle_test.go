@@ -0,0 +1,108 @@
+package ethereum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+)
+
+// newFeeNode returns a node answering the fee requests of the fee oracle,
+// without eth_maxPriorityFeePerGas if noMaxPriorityFee is set.
+func newFeeNode(t *testing.T, noMaxPriorityFee bool) *testNode {
+	node := newTestNode(t)
+	node.handle("eth_gasPrice", result(`"0x64"`))
+	node.handle("eth_maxPriorityFeePerGas", func(*rpcRequest) (string, error) {
+		if noMaxPriorityFee {
+			return "", &codec.ErrorObject{Code: -32601, Message: "method not found"}
+		}
+		return `"0x3"`, nil
+	})
+	node.handle("eth_feeHistory", result(`{"oldestBlock":"0x1","baseFeePerGas":["0x8","0x9","0xa"],"reward":[["0x1"],["0x5"]],"gasUsedRatio":[0.5,0.5]}`))
+	return node
+}
+
+func Test_feeOracle(t *testing.T) {
+	t.Run("legacy", func(t *testing.T) {
+		node := newFeeNode(t, false)
+		c := newTestClient(t, node.url)
+		c.fees = newFeeOracle(c, feeOptions{Strategy: feeStrategyLegacy})
+
+		tx := Transaction{}
+		require.NoError(t, c.fillFees(&tx))
+		require.Equal(t, Wei("100"), tx.GasPrice)
+		require.True(t, tx.GasFeeCap.IsZero())
+
+		// cached until a new block or the ttl
+		require.NoError(t, c.fillFees(&Transaction{}))
+		require.Equal(t, 1, node.count("eth_gasPrice"))
+
+		c.fees.updated = time.Now().Add(-feeOracleTTL)
+		require.NoError(t, c.fillFees(&Transaction{}))
+		require.Equal(t, 2, node.count("eth_gasPrice"))
+
+		// fees given by the transaction are kept
+		tx = Transaction{GasTipCap: "1"}
+		require.NoError(t, c.fillFees(&tx))
+		require.True(t, tx.GasPrice.IsZero())
+		require.Equal(t, Wei("1"), tx.GasTipCap)
+	})
+
+	t.Run("eip1559", func(t *testing.T) {
+		node := newFeeNode(t, false)
+		c := newTestClient(t, node.url)
+		c.fees = newFeeOracle(c, feeOptions{Strategy: feeStrategyEIP1559})
+
+		tx := Transaction{}
+		require.NoError(t, c.fillFees(&tx))
+		// 2 * 10 + 3
+		require.Equal(t, Wei("23"), tx.GasFeeCap)
+		require.Equal(t, Wei("3"), tx.GasTipCap)
+	})
+
+	t.Run("eip1559 from fee history", func(t *testing.T) {
+		node := newFeeNode(t, true)
+		c := newTestClient(t, node.url)
+		c.fees = newFeeOracle(c, feeOptions{Strategy: feeStrategyEIP1559, BaseFeeMultiplier: 1.5})
+
+		tx := Transaction{}
+		require.NoError(t, c.fillFees(&tx))
+		// 1.5 * 10 + 5
+		require.Equal(t, Wei("20"), tx.GasFeeCap)
+		require.Equal(t, Wei("5"), tx.GasTipCap)
+
+		// eth_maxPriorityFeePerGas isn't requested again
+		c.fees.updated = time.Time{}
+		require.NoError(t, c.fillFees(&Transaction{}))
+		require.Equal(t, 1, node.count("eth_maxPriorityFeePerGas"))
+		require.Equal(t, 2, node.count("eth_feeHistory"))
+	})
+
+	t.Run("fixed", func(t *testing.T) {
+		node := newFeeNode(t, false)
+		c := newTestClient(t, node.url)
+		c.fees = newFeeOracle(c, feeOptions{Strategy: feeStrategyFixed, MaxFeePerGas: "0x10", MaxPriorityFeePerGas: "2"})
+
+		tx := Transaction{}
+		require.NoError(t, c.fillFees(&tx))
+		require.Equal(t, Wei("16"), tx.GasFeeCap)
+		require.Equal(t, Wei("2"), tx.GasTipCap)
+		require.Empty(t, node.calls)
+	})
+}
+
+func Test_feeOptions_validate(t *testing.T) {
+	require.NoError(t, (*feeOptions)(nil).validate())
+	require.NoError(t, (&feeOptions{Strategy: feeStrategyLegacy}).validate())
+	require.Error(t, (&feeOptions{Strategy: "cheap"}).validate())
+	require.Error(t, (&feeOptions{Strategy: feeStrategyFixed}).validate())
+	require.Error(t, (&feeOptions{Strategy: feeStrategyFixed, GasPrice: "1", MaxFeePerGas: "2"}).validate())
+	require.NoError(t, (&feeOptions{Strategy: feeStrategyFixed, GasPrice: "1"}).validate())
+
+	var opts options
+	require.NoError(t, decodeOptions(map[string]interface{}{
+		"fees": map[string]interface{}{"strategy": "fixed", "gasPrice": 1000000000},
+	}, &opts))
+	require.Equal(t, Wei("1000000000"), opts.Fees.GasPrice)
+}
@@ -0,0 +1,347 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/wallet"
+)
+
+const (
+	defaultFundBatchSize = 50
+	// defaultFundTimeout is how long a funding or sweep transaction is waited
+	// for when no timeout is given.
+	defaultFundTimeout = 2 * time.Minute
+	// sweepFeeBump is the increase in percent of the fees of sweeps over the
+	// current ones, so they're mined even if the base fee rises meanwhile.
+	sweepFeeBump = 25
+)
+
+// transferGas is the gas of a plain transfer.
+const transferGas = 21000
+
+// FundOptions are the options of FundAccounts.
+type FundOptions struct {
+	// From is the client whose first account funds the new accounts.
+	From *Client
+	// Count is the number of accounts created.
+	Count int
+	// Amount is the wei sent to every account.
+	Amount Wei
+	// BatchSize is the number of funding transactions sent in parallel, defaults to 50.
+	BatchSize int `js:"batchSize"`
+	// Timeout fails the funding if a transaction isn't mined in time, defaults to 2m.
+	Timeout interface{}
+}
+
+// SweepOptions are the options of Sweep.
+type SweepOptions struct {
+	// To is the client whose first account receives the funds.
+	To *Client
+	// Accounts are the accounts returned by FundAccounts.
+	Accounts []Key
+	// BatchSize is the number of accounts swept in parallel, defaults to 50.
+	BatchSize int `js:"batchSize"`
+	// Timeout fails the sweep if a transaction isn't mined in time, defaults to 2m.
+	Timeout interface{}
+}
+
+// FundAccounts creates count accounts and sends them amount from the first
+// account of the from client, in parallel batches of transactions. It waits
+// for every transaction to be mined, checks the balances of the accounts and
+// returns them. Meant to be called in setup.
+func (mi *ModuleInstance) FundAccounts(opts FundOptions) ([]*Key, error) {
+	c := opts.From
+	if c == nil {
+		return nil, errors.New("from must be a client")
+	}
+	if opts.Count <= 0 {
+		return nil, errors.New("count must be positive")
+	}
+	amount, err := opts.Amount.Int()
+	if err != nil {
+		return nil, err
+	}
+	if amount.Sign() <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultFundBatchSize
+	}
+	timeout, err := fundTimeout(opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	funder, err := c.accounts.lookup("0")
+	if err != nil {
+		return nil, err
+	}
+	nonces := funder.nonces
+	if nonces == nil {
+		address := funder.key.Address().String()
+		nonces = newNonceManager(func() (uint64, error) {
+			return c.GetNonce(address)
+		})
+	}
+
+	// the oracle fills the fees if enabled, otherwise the gas price is
+	// fetched once for all the transactions
+	var gasPrice Wei
+	if c.fees == nil {
+		gp, err := c.GasPrice()
+		if err != nil {
+			return nil, err
+		}
+		gasPrice = Wei(gp)
+	}
+
+	keys := make([]*Key, opts.Count)
+	for i := range keys {
+		k, err := newKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = k
+	}
+
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+
+		first, err := nonces.reserve(len(batch))
+		if err != nil {
+			return nil, err
+		}
+
+		// the transactions of higher nonces are stuck if one fails, so the
+		// rest of the batch isn't waited for
+		err = parallel(c.vuContext(), len(batch), func(ctx context.Context, i int) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			tx := Transaction{
+				To:       batch[i].Address,
+				Value:    Wei(weiString(amount)),
+				Gas:      transferGas,
+				GasPrice: gasPrice,
+				Nonce:    first + uint64(i),
+			}
+			hash, err := c.sendRawTransaction(funder.key, tx)
+			if err != nil {
+				return fmt.Errorf("failed to fund %s: %w", batch[i].Address, err)
+			}
+			return c.waitForTransfer(ctx, hash, timeout)
+		})
+		if err != nil {
+			// the nonces of the failed transactions were skipped
+			nonces.reset()
+			return nil, err
+		}
+
+		err = parallel(c.vuContext(), len(batch), func(_ context.Context, i int) error {
+			balance, err := c.GetBalance(batch[i].Address, ethgo.Latest)
+			if err != nil {
+				return err
+			}
+			if b, _ := new(big.Int).SetString(balance, 10); b == nil || b.Cmp(amount) < 0 {
+				return fmt.Errorf("account %s has a balance of %s after being funded with %s", batch[i].Address, balance, amount)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// Sweep sends the balance of the accounts, less the fees, back to the first
+// account of the to client and returns the total amount swept in wei. Meant
+// to be called in teardown.
+func (mi *ModuleInstance) Sweep(opts SweepOptions) (string, error) {
+	c := opts.To
+	if c == nil {
+		return "", errors.New("to must be a client")
+	}
+	timeout, err := fundTimeout(opts.Timeout)
+	if err != nil {
+		return "", err
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultFundBatchSize
+	}
+
+	receiver, err := c.accounts.lookup("0")
+	if err != nil {
+		return "", err
+	}
+
+	var lock sync.Mutex
+	total := new(big.Int)
+	sweep := func(ctx context.Context, account Key, fees Transaction, fee *big.Int) error {
+		pk, err := hex.DecodeString(strings.TrimPrefix(account.PrivateKey, "0x"))
+		if err != nil {
+			return fmt.Errorf("invalid private key of %s: %w", account.Address, err)
+		}
+		key, err := wallet.NewWalletFromPrivKey(pk)
+		if err != nil {
+			return fmt.Errorf("invalid private key of %s: %w", account.Address, err)
+		}
+		address := key.Address().String()
+
+		balance, err := c.GetBalance(address, ethgo.Latest)
+		if err != nil {
+			return err
+		}
+		value, _ := new(big.Int).SetString(balance, 10)
+		if value == nil || value.Cmp(fee) <= 0 {
+			return nil
+		}
+		value.Sub(value, fee)
+
+		nonce, err := c.GetNonce(address)
+		if err != nil {
+			return err
+		}
+
+		tx := fees
+		tx.To = receiver.key.Address().String()
+		tx.Value = Wei(weiString(value))
+		tx.Gas = transferGas
+		tx.Nonce = nonce
+		hash, err := c.sendRawTransaction(key, tx)
+		if err != nil {
+			return fmt.Errorf("failed to sweep %s: %w", address, err)
+		}
+		if err := c.waitForTransfer(ctx, hash, timeout); err != nil {
+			return err
+		}
+
+		lock.Lock()
+		total.Add(total, value)
+		lock.Unlock()
+		return nil
+	}
+
+	for start := 0; start < len(opts.Accounts); start += batchSize {
+		end := start + batchSize
+		if end > len(opts.Accounts) {
+			end = len(opts.Accounts)
+		}
+		batch := opts.Accounts[start:end]
+
+		// the fees are taken again for every batch
+		fees, fee, err := c.sweepFees()
+		if err != nil {
+			return "", err
+		}
+		err = parallel(c.vuContext(), len(batch), func(ctx context.Context, i int) error {
+			return sweep(ctx, batch[i], fees, fee)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return weiString(total), nil
+}
+
+// sweepFees returns a transaction with the fees of the fee oracle, or the gas
+// price of the node without it, raised by sweepFeeBump. The fee of a transfer
+// at the highest price per gas it can pay is returned with it.
+func (c *Client) sweepFees() (Transaction, *big.Int, error) {
+	var tx Transaction
+	if c.fees == nil {
+		gasPrice, err := c.GasPrice()
+		if err != nil {
+			return Transaction{}, nil, err
+		}
+		tx.GasPrice = Wei(gasPrice)
+	} else if err := c.fillFees(&tx); err != nil {
+		return Transaction{}, nil, err
+	}
+	if err := c.bumpFees(&tx, sweepFeeBump); err != nil {
+		return Transaction{}, nil, err
+	}
+
+	price := tx.GasPrice
+	if !tx.GasFeeCap.IsZero() {
+		price = tx.GasFeeCap
+	}
+	fee, err := price.Int()
+	if err != nil {
+		return Transaction{}, nil, err
+	}
+
+	return tx, fee.Mul(fee, big.NewInt(transferGas)), nil
+}
+
+// fundTimeout returns the timeout of FundAccounts and Sweep, defaultFundTimeout
+// if not set.
+func fundTimeout(v interface{}) (time.Duration, error) {
+	timeout, err := optionalDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %w", err)
+	}
+	if timeout <= 0 {
+		timeout = defaultFundTimeout
+	}
+
+	return timeout, nil
+}
+
+// waitForTransfer waits for the transaction with the given hash to be mined
+// and fails if it reverted.
+func (c *Client) waitForTransfer(ctx context.Context, hash string, timeout time.Duration) error {
+	receipt, err := c.waitForReceipt(ctx, hash, timeout, 0)
+	if err != nil {
+		return err
+	}
+	if receipt.Status == 0 {
+		return fmt.Errorf("transaction %s failed", hash)
+	}
+
+	return nil
+}
+
+// parallel runs fn for every index from 0 to n in its own goroutine and
+// returns the first error. The context given to fn is canceled once any of
+// them fails.
+func parallel(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var first error
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return first
+}
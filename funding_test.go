@@ -0,0 +1,146 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/wallet"
+)
+
+// transferNode mines the transfers sent to it as soon as they arrive, with
+// a gas price of 1.
+type transferNode struct {
+	*testNode
+	balances map[ethgo.Address]*big.Int
+	nonces   map[ethgo.Address]map[uint64]bool
+	mined    map[ethgo.Hash]ethgo.Address
+}
+
+func newTransferNode(t *testing.T, balances map[ethgo.Address]*big.Int) *transferNode {
+	n := &transferNode{
+		testNode: newTestNode(t),
+		balances: balances,
+		nonces:   map[ethgo.Address]map[uint64]bool{},
+		mined:    map[ethgo.Hash]ethgo.Address{},
+	}
+
+	n.handle("eth_gasPrice", result(`"0x1"`))
+	n.handle("eth_getBalance", func(req *rpcRequest) (string, error) {
+		return fmt.Sprintf(`"0x%x"`, n.balance(ethgo.HexToAddress(req.params[0].(string)))), nil
+	})
+	n.handle("eth_getTransactionCount", func(req *rpcRequest) (string, error) {
+		return fmt.Sprintf(`"0x%x"`, len(n.nonces[ethgo.HexToAddress(req.params[0].(string))])), nil
+	})
+	n.handle("eth_sendRawTransaction", n.send)
+	n.handle("eth_getTransactionReceipt", func(req *rpcRequest) (string, error) {
+		h := ethgo.HexToHash(req.params[0].(string))
+		if from, ok := n.mined[h]; ok {
+			return testReceipt(from, h), nil
+		}
+		return "null", nil
+	})
+
+	return n
+}
+
+func (n *transferNode) balance(a ethgo.Address) *big.Int {
+	if b, ok := n.balances[a]; ok {
+		return b
+	}
+	n.balances[a] = new(big.Int)
+	return n.balances[a]
+}
+
+func (n *transferNode) send(req *rpcRequest) (string, error) {
+	raw, _ := hex.DecodeString(strings.TrimPrefix(req.params[0].(string), "0x"))
+	tx := new(ethgo.Transaction)
+	if err := tx.UnmarshalRLP(raw); err != nil {
+		return "", err
+	}
+	from, err := wallet.NewEIP155Signer(1337).RecoverSender(tx)
+	if err != nil {
+		return "", err
+	}
+	if n.nonces[from] == nil {
+		n.nonces[from] = map[uint64]bool{}
+	}
+	if n.nonces[from][tx.Nonce] {
+		return "", errors.New("nonce too low")
+	}
+	cost := new(big.Int).Add(tx.Value, big.NewInt(int64(tx.Gas*tx.GasPrice)))
+	if n.balance(from).Cmp(cost) < 0 {
+		return "", errors.New("insufficient funds")
+	}
+	n.nonces[from][tx.Nonce] = true
+	n.balance(from).Sub(n.balance(from), cost)
+	n.balance(*tx.To).Add(n.balance(*tx.To), tx.Value)
+	n.mined[tx.Hash] = from
+	return `"` + tx.Hash.String() + `"`, nil
+}
+
+func Test_fundAccounts(t *testing.T) {
+	pk, _ := hex.DecodeString("42b6e34dc21598a807dc19d7784c71b2a7a01f6480dc6f58258f78e539f1a1fa")
+	wa, err := wallet.NewWalletFromPrivKey(pk)
+	require.NoError(t, err)
+
+	node := newTransferNode(t, map[ethgo.Address]*big.Int{wa.Address(): big.NewInt(1_000_000)})
+	c := newTestClient(t, node.url, wa)
+	mi := &ModuleInstance{}
+
+	keys, err := mi.FundAccounts(FundOptions{From: c, Count: 5, Amount: "100000", BatchSize: 2})
+	require.NoError(t, err)
+	require.Len(t, keys, 5)
+	for _, k := range keys {
+		require.Equal(t, big.NewInt(100000), node.balances[ethgo.HexToAddress(k.Address)])
+	}
+	// 5 transfers and their fees
+	require.Equal(t, big.NewInt(1_000_000-5*(100000+21000)), node.balances[wa.Address()])
+	require.Len(t, node.nonces[wa.Address()], 5)
+
+	// the funder can't afford more accounts
+	_, err = mi.FundAccounts(FundOptions{From: c, Count: 5, Amount: "100000"})
+	require.ErrorContains(t, err, "insufficient funds")
+
+	accounts := make([]Key, len(keys))
+	for i, k := range keys {
+		accounts[i] = *k
+	}
+	swept, err := mi.Sweep(SweepOptions{To: c, Accounts: accounts, BatchSize: 3})
+	require.NoError(t, err)
+	// at the gas price of the node bumped to 2
+	require.Equal(t, weiString(big.NewInt(5*(100000-2*21000))), swept)
+	for _, k := range keys {
+		require.Zero(t, node.balances[ethgo.HexToAddress(k.Address)].Sign())
+	}
+
+	_, err = mi.FundAccounts(FundOptions{From: c, Count: 0, Amount: "1"})
+	require.Error(t, err)
+}
+
+func Test_parallel(t *testing.T) {
+	failed := errors.New("failed")
+
+	// the others are canceled by the first error
+	err := parallel(context.Background(), 3, func(ctx context.Context, i int) error {
+		if i == 0 {
+			return failed
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	})
+	require.ErrorIs(t, err, failed)
+
+	require.NoError(t, parallel(context.Background(), 3, func(context.Context, int) error { return nil }))
+}
@@ -1,39 +1,31 @@
 import eth from 'k6/x/ethereum';
 import exec from 'k6/execution';
-import wallet from 'k6/x/ethereum/wallet';
 
+// fundTestAccounts creates an account per VU funded by the account of
+// priv_key, root_address is kept for compatibility.
 export function fundTestAccounts(root_address, url, priv_key) {
-    const client = new eth.Client({ 
+    const client = new eth.Client({
         url: url,
         privateKey: priv_key,
     });
-    var accounts = [];
-    var nonce = client.getNonce(root_address);
 
-    // fund the VUs accounts
-    for (let i = 0; i < exec.instance.vusInitialized; i++) {
-        var tacc = wallet.generateKey();
-        accounts[i] = {
-            private_key: tacc.private_key,
-            address: tacc.address,
-        };
-
-        // fund each account with 5 ETH
-        var tx = {
-            to: tacc.address,
-            value: Number(0.05 * 1e18),
-            gas_price: client.gasPrice(),
-            nonce: nonce,
-        };
-
-        console.log(JSON.stringify(tx));
-        var txh = client.sendRawTransaction(tx)
-        client.waitForTransactionReceipt(txh).then((receipt) => {
-            console.log(`account funded => ${receipt.block_hash}`);
-        });
+    // fund each account with 0.05 ETH
+    return eth.fundAccounts({
+        from: client,
+        count: exec.instance.vusInitialized,
+        amount: "50000000000000000",
+    });
+}
 
-        nonce++;
-    }
+// sweepTestAccounts returns the funds left in the accounts to the account of priv_key.
+export function sweepTestAccounts(accounts, url, priv_key) {
+    const client = new eth.Client({
+        url: url,
+        privateKey: priv_key,
+    });
 
-    return accounts;
+    return eth.sweep({
+        to: client,
+        accounts: accounts,
+    });
 }
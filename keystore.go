@@ -0,0 +1,150 @@
+package ethereum
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/umbracle/ethgo/keystore"
+	"github.com/umbracle/ethgo/wallet"
+)
+
+// defaultScryptN is the scrypt cost parameter of the keystores written, as
+// used by geth.
+const defaultScryptN = 1 << 18
+
+// KeystoreOptions are the options of ToKeystore.
+type KeystoreOptions struct {
+	// ScryptN is the scrypt cost parameter, a power of 2. Defaults to 262144,
+	// lower values make the keystore faster to decrypt and weaker.
+	ScryptN int `js:"scryptN"`
+}
+
+// FromKeystore decrypts a keystore in the Web3 Secret Storage format, as
+// written by geth or clef, with the given password.
+func (w *Wallet) FromKeystore(keystore string, password string) (*Key, error) {
+	k, err := decryptKeystore([]byte(keystore), password)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKeyFrom(k)
+}
+
+// ToKeystore encrypts key with the given password in the Web3 Secret Storage
+// format and returns the keystore json.
+func (w *Wallet) ToKeystore(key Key, password string, opts KeystoreOptions) (string, error) {
+	pk, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(key.PrivateKey), "0x"))
+	if err != nil {
+		return "", fmt.Errorf("invalid private key: %w", err)
+	}
+	k, err := wallet.NewWalletFromPrivKey(pk)
+	if err != nil {
+		return "", fmt.Errorf("invalid private key: %w", err)
+	}
+
+	keystore, err := encryptKeystore(k, password, opts.ScryptN)
+	if err != nil {
+		return "", err
+	}
+
+	return string(keystore), nil
+}
+
+// decryptKeystore returns the key of a keystore encrypted with scrypt or pbkdf2.
+func decryptKeystore(keystore []byte, password string) (*wallet.Key, error) {
+	k, err := wallet.NewJSONWalletFromContent(keystore, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+	}
+
+	return k, nil
+}
+
+// encryptKeystore encrypts the private key of k with scrypt and aes-128-ctr,
+// adding the address and id fields keystore.EncryptV3 leaves out and writing
+// its iv in lower case as other implementations expect.
+func encryptKeystore(k *wallet.Key, password string, scryptN int) ([]byte, error) {
+	if scryptN == 0 {
+		scryptN = defaultScryptN
+	}
+	if scryptN <= 1 || scryptN&(scryptN-1) != 0 {
+		return nil, errors.New("scryptN must be a power of 2 greater than 1")
+	}
+
+	pk, err := k.MarshallPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := keystore.EncryptV3(pk, password, scryptN)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encrypted, &fields); err != nil {
+		return nil, err
+	}
+	if crypto, ok := fields["crypto"].(map[string]interface{}); ok {
+		if params, ok := crypto["cipherparams"].(map[string]interface{}); ok {
+			if iv, ok := params["IV"]; ok {
+				delete(params, "IV")
+				params["iv"] = iv
+			}
+		}
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	fields["address"] = strings.ToLower(strings.TrimPrefix(k.Address().String(), "0x"))
+	fields["id"] = uuidV4(id)
+
+	return json.Marshal(fields)
+}
+
+// keystoreCache holds the keys of the keystores decrypted by the clients of
+// every VU, as decrypting a keystore with scrypt takes up to seconds.
+type keystoreCache struct {
+	keys sync.Map
+}
+
+type cachedKey struct {
+	once sync.Once
+	key  *wallet.Key
+	err  error
+}
+
+// decrypt returns the key of keystore, decrypted only by the first caller
+// with the same keystore and password.
+func (kc *keystoreCache) decrypt(keystore []byte, password string) (*wallet.Key, error) {
+	h := sha256.New()
+	binary.Write(h, binary.BigEndian, uint64(len(keystore)))
+	h.Write(keystore)
+	h.Write([]byte(password))
+	var id [sha256.Size]byte
+	copy(id[:], h.Sum(nil))
+
+	v, _ := kc.keys.LoadOrStore(id, &cachedKey{})
+	ck := v.(*cachedKey)
+	ck.once.Do(func() {
+		ck.key, ck.err = decryptKeystore(keystore, password)
+	})
+
+	return ck.key, ck.err
+}
+
+// uuidV4 formats 16 random bytes as a version 4 uuid.
+func uuidV4(b []byte) string {
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,103 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// web3Keystore is the pbkdf2 test vector of the Web3 Secret Storage definition.
+const web3Keystore = `{
+	"crypto": {
+		"cipher": "aes-128-ctr",
+		"cipherparams": {"iv": "6087dab2f9fdbbfaddc31a909735c1e6"},
+		"ciphertext": "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+		"kdf": "pbkdf2",
+		"kdfparams": {
+			"c": 262144,
+			"dklen": 32,
+			"prf": "hmac-sha256",
+			"salt": "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"
+		},
+		"mac": "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+	},
+	"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+	"version": 3
+}`
+
+func Test_FromKeystore(t *testing.T) {
+	w := &Wallet{}
+
+	key, err := w.FromKeystore(web3Keystore, "testpassword")
+	require.NoError(t, err)
+	require.Equal(t, "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d", key.PrivateKey)
+	require.Equal(t, "0x008AeEda4D805471dF9b2A5B0f38A0C3bCBA786b", key.Address)
+
+	_, err = w.FromKeystore(web3Keystore, "wrong")
+	require.Error(t, err)
+}
+
+func Test_ToKeystore(t *testing.T) {
+	w := &Wallet{}
+
+	key, err := w.GenerateKey()
+	require.NoError(t, err)
+
+	keystore, err := w.ToKeystore(*key, "secret", KeystoreOptions{ScryptN: 1 << 10})
+	require.NoError(t, err)
+
+	var fields struct {
+		Address string
+		ID      string
+		Version int
+		Crypto  struct {
+			Cipher       string
+			CipherParams map[string]string
+			KDF          string
+			KDFParams    map[string]interface{}
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(keystore), &fields))
+	require.Equal(t, strings.ToLower(key.Address[2:]), fields.Address)
+	require.Equal(t, 3, fields.Version)
+	require.Len(t, fields.ID, 36)
+	require.Equal(t, byte('4'), fields.ID[14])
+	require.Equal(t, "aes-128-ctr", fields.Crypto.Cipher)
+	require.Contains(t, fields.Crypto.CipherParams, "iv")
+	require.Equal(t, "scrypt", fields.Crypto.KDF)
+	require.Equal(t, float64(1<<10), fields.Crypto.KDFParams["n"])
+
+	decrypted, err := w.FromKeystore(keystore, "secret")
+	require.NoError(t, err)
+	require.Equal(t, key, decrypted)
+
+	_, err = w.FromKeystore(keystore, "wrong")
+	require.Error(t, err)
+
+	_, err = w.ToKeystore(*key, "secret", KeystoreOptions{ScryptN: 1000})
+	require.Error(t, err)
+	_, err = w.ToKeystore(Key{PrivateKey: "zz"}, "secret", KeystoreOptions{})
+	require.Error(t, err)
+}
+
+func Test_keystoreCache(t *testing.T) {
+	w := &Wallet{}
+	key, err := w.GenerateKey()
+	require.NoError(t, err)
+	keystore, err := w.ToKeystore(*key, "secret", KeystoreOptions{ScryptN: 1 << 10})
+	require.NoError(t, err)
+
+	// decrypted once for every client with the same keystore and password
+	kc := &keystoreCache{}
+	first, err := kc.decrypt([]byte(keystore), "secret")
+	require.NoError(t, err)
+	require.Equal(t, key.Address, first.Address().String())
+	second, err := kc.decrypt([]byte(keystore), "secret")
+	require.NoError(t, err)
+	require.Same(t, first, second)
+
+	_, err = kc.decrypt([]byte(keystore), "wrong")
+	require.Error(t, err)
+}
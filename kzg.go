@@ -0,0 +1,227 @@
+package ethereum
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// KZG commitments and proofs for eip-4844 blobs, following the polynomial
+// commitments of the deneb consensus specs.
+
+const (
+	fieldElementsPerBlob = 4096
+	bytesPerFieldElement = 32
+	blobSize             = fieldElementsPerBlob * bytesPerFieldElement
+	kzgPointSize         = 48
+)
+
+var (
+	blsModulus, _    = new(big.Int).SetString("52435875175126190479447740508185965837690552500527637822603658699938581184513", 10)
+	fiatShamirDomain = []byte("FSBLOBVERIFY_V1_")
+	rootsOfUnityBRP  = computeRootsOfUnityBRP()
+)
+
+func parseKZGSetup(data string) ([]string, error) {
+	data = strings.TrimSpace(data)
+
+	if strings.HasPrefix(data, "{") {
+		var setup struct {
+			G1Lagrange []string `json:"g1_lagrange"`
+		}
+		if err := json.Unmarshal([]byte(data), &setup); err != nil {
+			return nil, fmt.Errorf("invalid trusted setup: %w", err)
+		}
+		if len(setup.G1Lagrange) != fieldElementsPerBlob {
+			return nil, fmt.Errorf("invalid trusted setup: expected %d g1 points", fieldElementsPerBlob)
+		}
+		return setup.G1Lagrange, nil
+	}
+
+	fields := strings.Fields(data)
+	if len(fields) < 2+fieldElementsPerBlob {
+		return nil, fmt.Errorf("invalid trusted setup: expected %d g1 points", fieldElementsPerBlob)
+	}
+	if n, err := strconv.Atoi(fields[0]); err != nil || n != fieldElementsPerBlob {
+		return nil, fmt.Errorf("invalid trusted setup: expected %d g1 points", fieldElementsPerBlob)
+	}
+
+	return fields[2 : 2+fieldElementsPerBlob], nil
+}
+
+// blobToCommitment returns the KZG commitment of a blob.
+func (s *kzgSetup) blobToCommitment(blob []byte) ([]byte, error) {
+	poly, err := blobToPolynomial(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.lincomb(poly), nil
+}
+
+// computeBlobProof returns the KZG proof of a blob for its commitment,
+// evaluated at the Fiat-Shamir challenge.
+func (s *kzgSetup) computeBlobProof(blob []byte, commitment []byte) ([]byte, error) {
+	poly, err := blobToPolynomial(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	z := computeChallenge(blob, commitment)
+	y := evaluatePolynomial(poly, z)
+
+	quotient := make([]*big.Int, fieldElementsPerBlob)
+	denominators := make([]*big.Int, fieldElementsPerBlob)
+	for i, root := range rootsOfUnityBRP {
+		denominators[i] = frSub(root, z)
+	}
+	inverses := batchInverse(denominators)
+
+	for i, root := range rootsOfUnityBRP {
+		if denominators[i].Sign() == 0 {
+			quotient[i] = quotientWithinDomain(root, poly, y)
+			continue
+		}
+		quotient[i] = frMul(frSub(poly[i], y), inverses[i])
+	}
+
+	return s.lincomb(quotient), nil
+}
+
+// blobVersionedHash returns the versioned hash of a KZG commitment.
+func blobVersionedHash(commitment []byte) [32]byte {
+	h := sha256.Sum256(commitment)
+	h[0] = 0x01
+	return h
+}
+
+func blobToPolynomial(blob []byte) ([]*big.Int, error) {
+	if len(blob) != blobSize {
+		return nil, fmt.Errorf("invalid blob size %d", len(blob))
+	}
+
+	poly := make([]*big.Int, fieldElementsPerBlob)
+	for i := range poly {
+		poly[i] = new(big.Int).SetBytes(blob[i*bytesPerFieldElement : (i+1)*bytesPerFieldElement])
+		if poly[i].Cmp(blsModulus) >= 0 {
+			return nil, fmt.Errorf("blob field element %d is not canonical", i)
+		}
+	}
+
+	return poly, nil
+}
+
+func computeChallenge(blob []byte, commitment []byte) *big.Int {
+	degree := make([]byte, 16)
+	binary.BigEndian.PutUint64(degree[8:], fieldElementsPerBlob)
+
+	h := sha256.New()
+	h.Write(fiatShamirDomain)
+	h.Write(degree)
+	h.Write(blob)
+	h.Write(commitment)
+
+	return new(big.Int).Mod(new(big.Int).SetBytes(h.Sum(nil)), blsModulus)
+}
+
+// evaluatePolynomial evaluates a polynomial in evaluation form at z using the
+// barycentric formula.
+func evaluatePolynomial(poly []*big.Int, z *big.Int) *big.Int {
+	denominators := make([]*big.Int, fieldElementsPerBlob)
+	for i, root := range rootsOfUnityBRP {
+		if root.Cmp(z) == 0 {
+			return new(big.Int).Set(poly[i])
+		}
+		denominators[i] = frSub(z, root)
+	}
+	inverses := batchInverse(denominators)
+
+	result := new(big.Int)
+	for i, root := range rootsOfUnityBRP {
+		result.Add(result, frMul(frMul(poly[i], root), inverses[i]))
+	}
+	result.Mod(result, blsModulus)
+
+	width := big.NewInt(fieldElementsPerBlob)
+	r := frSub(new(big.Int).Exp(z, width, blsModulus), big.NewInt(1))
+	r = frMul(r, new(big.Int).ModInverse(width, blsModulus))
+
+	return frMul(result, r)
+}
+
+// quotientWithinDomain computes the quotient evaluation at a root of unity z.
+func quotientWithinDomain(z *big.Int, poly []*big.Int, y *big.Int) *big.Int {
+	result := new(big.Int)
+	for i, root := range rootsOfUnityBRP {
+		if root.Cmp(z) == 0 {
+			continue
+		}
+		numerator := frMul(frSub(poly[i], y), root)
+		denominator := frMul(z, frSub(z, root))
+		result.Add(result, frMul(numerator, new(big.Int).ModInverse(denominator, blsModulus)))
+	}
+
+	return result.Mod(result, blsModulus)
+}
+
+func computeRootsOfUnityBRP() []*big.Int {
+	exp := new(big.Int).Div(new(big.Int).Sub(blsModulus, big.NewInt(1)), big.NewInt(fieldElementsPerBlob))
+	root := new(big.Int).Exp(big.NewInt(7), exp, blsModulus)
+
+	roots := make([]*big.Int, fieldElementsPerBlob)
+	current := big.NewInt(1)
+	for i := range roots {
+		roots[bitReverse(i)] = current
+		current = frMul(current, root)
+	}
+
+	return roots
+}
+
+// bitReverse reverses the bits of an index of the evaluation domain.
+func bitReverse(i int) int {
+	r := 0
+	for n := fieldElementsPerBlob; n > 1; n >>= 1 {
+		r = r<<1 | i&1
+		i >>= 1
+	}
+	return r
+}
+
+// batchInverse inverts all the non zero elements with a single modular inversion.
+func batchInverse(elems []*big.Int) []*big.Int {
+	out := make([]*big.Int, len(elems))
+	acc := big.NewInt(1)
+	for i, e := range elems {
+		out[i] = acc
+		if e.Sign() != 0 {
+			acc = frMul(acc, e)
+		}
+	}
+
+	inv := new(big.Int).ModInverse(acc, blsModulus)
+	for i := len(elems) - 1; i >= 0; i-- {
+		if elems[i].Sign() == 0 {
+			out[i] = new(big.Int)
+			continue
+		}
+		out[i] = frMul(out[i], inv)
+		inv = frMul(inv, elems[i])
+	}
+
+	return out
+}
+
+func frMul(a, b *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Mod(r, blsModulus)
+}
+
+func frSub(a, b *big.Int) *big.Int {
+	r := new(big.Int).Sub(a, b)
+	return r.Mod(r, blsModulus)
+}
@@ -0,0 +1,71 @@
+//go:build cgo
+
+package ethereum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+// The group operations of the KZG commitments are done by blst, which requires
+// cgo.
+
+// setups caches the parsed trusted setups so every VU doesn't parse them again.
+var setups sync.Map
+
+// kzgSetup is the G1 part of a KZG trusted setup in lagrange form.
+type kzgSetup struct {
+	// g1Lagrange is bit reversal permuted to match the blob evaluations order
+	g1Lagrange blst.P1Affines
+}
+
+// loadKZGSetup parses a trusted setup in the text format used by c-kzg and
+// geth, or in the json format of the consensus specs.
+func loadKZGSetup(data string) (*kzgSetup, error) {
+	key := sha256.Sum256([]byte(data))
+	if s, ok := setups.Load(key); ok {
+		return s.(*kzgSetup), nil
+	}
+
+	points, err := parseKZGSetup(data)
+	if err != nil {
+		return nil, err
+	}
+
+	g1 := make(blst.P1Affines, fieldElementsPerBlob)
+	for i, p := range points {
+		b, err := hex.DecodeString(strings.TrimPrefix(p, "0x"))
+		if err != nil || len(b) != kzgPointSize {
+			return nil, fmt.Errorf("invalid trusted setup g1 point %d", i)
+		}
+		point := new(blst.P1Affine).Uncompress(b)
+		if point == nil || !point.InG1() {
+			return nil, fmt.Errorf("invalid trusted setup g1 point %d", i)
+		}
+		g1[bitReverse(i)] = *point
+	}
+
+	s, _ := setups.LoadOrStore(key, &kzgSetup{g1Lagrange: g1})
+	return s.(*kzgSetup), nil
+}
+
+// lincomb returns the compressed linear combination of the setup points by scalars.
+func (s *kzgSetup) lincomb(scalars []*big.Int) []byte {
+	// blst expects little endian scalars
+	le := make([][]byte, len(scalars))
+	for i, sc := range scalars {
+		b := sc.FillBytes(make([]byte, bytesPerFieldElement))
+		for l, r := 0, len(b)-1; l < r; l, r = l+1, r-1 {
+			b[l], b[r] = b[r], b[l]
+		}
+		le[i] = b
+	}
+
+	return s.g1Lagrange.Mult(le, 255).Compress()
+}
@@ -0,0 +1,74 @@
+//go:build cgo
+
+package ethereum
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+// testSetup returns a trusted setup with a known secret and the secret.
+func testSetup(t *testing.T) (*kzgSetup, *big.Int) {
+	t.Helper()
+
+	tau := big.NewInt(1234567)
+	natural := make([]*big.Int, fieldElementsPerBlob)
+	for i, root := range rootsOfUnityBRP {
+		natural[bitReverse(i)] = root
+	}
+
+	// the lagrange basis at tau: (tau^n - 1) / n * root / (tau - root)
+	n := big.NewInt(fieldElementsPerBlob)
+	factor := frMul(frSub(new(big.Int).Exp(tau, n, blsModulus), big.NewInt(1)), new(big.Int).ModInverse(n, blsModulus))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d\n65\n", fieldElementsPerBlob)
+	for _, root := range natural {
+		l := frMul(factor, frMul(root, new(big.Int).ModInverse(frSub(tau, root), blsModulus)))
+		fmt.Fprintln(&sb, hex.EncodeToString(g1Mul(l)))
+	}
+
+	setup, err := loadKZGSetup(sb.String())
+	require.NoError(t, err)
+
+	return setup, tau
+}
+
+func g1Mul(s *big.Int) []byte {
+	b := s.FillBytes(make([]byte, bytesPerFieldElement))
+	for l, r := 0, len(b)-1; l < r; l, r = l+1, r-1 {
+		b[l], b[r] = b[r], b[l]
+	}
+	return blst.P1Generator().Mult(b, 255).Compress()
+}
+
+func Test_KZG(t *testing.T) {
+	setup, tau := testSetup(t)
+
+	blob, err := blobFromBytes([]byte("hello blobs"))
+	require.NoError(t, err)
+
+	commitment, err := setup.blobToCommitment(blob)
+	require.NoError(t, err)
+
+	poly, err := blobToPolynomial(blob)
+	require.NoError(t, err)
+	require.Equal(t, g1Mul(evaluatePolynomial(poly, tau)), commitment)
+
+	proof, err := setup.computeBlobProof(blob, commitment)
+	require.NoError(t, err)
+
+	z := computeChallenge(blob, commitment)
+	y := evaluatePolynomial(poly, z)
+	q := frMul(frSub(evaluatePolynomial(poly, tau), y), new(big.Int).ModInverse(frSub(tau, z), blsModulus))
+	require.Equal(t, g1Mul(q), proof)
+
+	h := blobVersionedHash(commitment)
+	require.Equal(t, byte(0x01), h[0])
+}
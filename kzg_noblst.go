@@ -0,0 +1,22 @@
+//go:build !cgo
+
+package ethereum
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// kzgSetup is never loaded without cgo, blst is needed for the group
+// operations of the KZG commitments.
+type kzgSetup struct{}
+
+// loadKZGSetup fails, so clients have no setup and blob transactions are
+// rejected.
+func loadKZGSetup(string) (*kzgSetup, error) {
+	return nil, fmt.Errorf("%w, which needs a build with cgo enabled", errTrustedSetupRequired)
+}
+
+func (s *kzgSetup) lincomb([]*big.Int) []byte {
+	return nil
+}
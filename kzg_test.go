@@ -0,0 +1,22 @@
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_blobFromBytes(t *testing.T) {
+	blob, err := blobFromBytes([]byte{1, 2, 3})
+	require.NoError(t, err)
+	require.Len(t, blob, blobSize)
+	require.Equal(t, []byte{0, 1, 2, 3}, blob[:4])
+
+	_, err = blobFromBytes(make([]byte, bytesPerBlobPayload+1))
+	require.Error(t, err)
+
+	blob, err = randomBlob()
+	require.NoError(t, err)
+	_, err = blobToPolynomial(blob)
+	require.NoError(t, err)
+}
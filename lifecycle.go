@@ -0,0 +1,289 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/umbracle/ethgo"
+	"go.k6.io/k6/metrics"
+)
+
+// txLifecycleExpiry is how long a transaction is followed for before it's
+// dropped, as transactions not included never leave the watcher otherwise.
+const txLifecycleExpiry = 30 * time.Minute
+
+// txLifecycle is a transaction sent by a client followed until it's included
+// and, if requested, confirmed and finalized.
+type txLifecycle struct {
+	client *Client
+	sent   time.Time
+	// tags are the tags of the VU that sent the transaction with its tx_type
+	tags *metrics.TagSet
+
+	confirmations uint64
+	finality      bool
+
+	block     uint64
+	confirmed bool
+	safe      bool
+	finalized bool
+}
+
+// done returns true once every metric of the transaction has been reported
+// and its block is too deep to be orphaned by a reorg detected by the monitor.
+func (tx *txLifecycle) done(head uint64) bool {
+	if tx.block == 0 {
+		return false
+	}
+
+	return (tx.confirmations == 0 || tx.confirmed) && (!tx.finality || (tx.safe && tx.finalized)) &&
+		(tx.finalized || head >= tx.block+reorgWindow)
+}
+
+// txWatcher follows the transactions sent to an endpoint through the blocks
+// fetched by its block monitor, which owns it. It's shared by all the clients
+// of every VU pointing to the same url.
+type txWatcher struct {
+	lock sync.Mutex
+	txs  map[ethgo.Hash]*txLifecycle
+}
+
+// lifecycleSample is a lifecycle metric of a transaction pushed by its client.
+type lifecycleSample struct {
+	client *Client
+	metric *metrics.Metric
+	tags   *metrics.TagSet
+	value  time.Duration
+}
+
+func newTxWatcher() *txWatcher {
+	return &txWatcher{txs: map[ethgo.Hash]*txLifecycle{}}
+}
+
+func (w *txWatcher) add(hash ethgo.Hash, tx *txLifecycle) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.txs[hash] = tx
+}
+
+// sent returns the time the transaction was sent, false if it's not followed.
+func (w *txWatcher) sent(hash ethgo.Hash) (time.Time, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	tx, ok := w.txs[hash]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return tx.sent, true
+}
+
+// included returns the inclusion samples of the followed transactions found in block.
+func (w *txWatcher) included(block *ethgo.Block) []lifecycleSample {
+	now := time.Now()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var samples []lifecycleSample
+	for _, hash := range block.TransactionsHashes {
+		tx, ok := w.txs[hash]
+		if !ok || tx.block != 0 {
+			continue
+		}
+		tx.block = block.Number
+		samples = append(samples, lifecycleSample{tx.client, tx.client.metrics.TimeToInclusion, tx.tags, now.Sub(tx.sent)})
+	}
+
+	return samples
+}
+
+// finality returns the numbers of the safe and finalized blocks if any
+// followed transaction waits for them.
+func (w *txWatcher) finality(ctx context.Context, e *endpoint) (uint64, uint64, error) {
+	w.lock.Lock()
+	waiting := false
+	for _, tx := range w.txs {
+		if tx.block != 0 && tx.finality && !tx.finalized {
+			waiting = true
+			break
+		}
+	}
+	w.lock.Unlock()
+
+	if !waiting {
+		return 0, 0, nil
+	}
+
+	safe, err := blockNumberOf(ctx, e, "safe")
+	if err != nil {
+		return 0, 0, err
+	}
+	finalized, err := blockNumberOf(ctx, e, "finalized")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return safe, finalized, nil
+}
+
+// blockNumberOf returns the number of the block with the given tag, 0 if the
+// node doesn't know it yet.
+func blockNumberOf(ctx context.Context, e *endpoint, tag string) (uint64, error) {
+	var header struct {
+		Number string `json:"number"`
+	}
+	var raw json.RawMessage
+	if err := e.call(ctx, "eth_getBlockByNumber", &raw, tag, false); err != nil {
+		return 0, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(header.Number, 0, 64)
+}
+
+// advance returns the confirmation and finality samples of the included
+// transactions and drops the ones done or expired.
+func (w *txWatcher) advance(head, safe, finalized uint64) []lifecycleSample {
+	now := time.Now()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var samples []lifecycleSample
+	for hash, tx := range w.txs {
+		if tx.block != 0 {
+			m := tx.client.metrics
+			if tx.confirmations > 0 && !tx.confirmed && head+1 >= tx.block+tx.confirmations {
+				tx.confirmed = true
+				tags := tx.tags.With("confirmations", strconv.FormatUint(tx.confirmations, 10))
+				samples = append(samples, lifecycleSample{tx.client, m.TimeToConfirmations, tags, now.Sub(tx.sent)})
+			}
+			if tx.finality && !tx.safe && safe >= tx.block {
+				tx.safe = true
+				samples = append(samples, lifecycleSample{tx.client, m.TimeToSafe, tx.tags, now.Sub(tx.sent)})
+			}
+			if tx.finality && !tx.finalized && finalized >= tx.block {
+				tx.finalized = true
+				samples = append(samples, lifecycleSample{tx.client, m.TimeToFinalized, tx.tags, now.Sub(tx.sent)})
+			}
+		}
+
+		if tx.done(head) || now.Sub(tx.sent) > txLifecycleExpiry {
+			delete(w.txs, hash)
+		}
+	}
+
+	return samples
+}
+
+// reorg makes the transactions included in the blocks orphaned from block
+// from on be found again in the canonical ones, reported with the reorged tag.
+func (w *txWatcher) reorg(from uint64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for _, tx := range w.txs {
+		if tx.block >= from {
+			tx.block = 0
+			tx.confirmed, tx.safe, tx.finalized = false, false, false
+			tx.tags = tx.tags.With("reorged", "true")
+		}
+	}
+}
+
+// pushLifecycle pushes the samples outside of the watcher lock, as pushing
+// blocks while the samples buffer is full.
+func pushLifecycle(samples []lifecycleSample) {
+	for _, s := range samples {
+		s.client.reportLifecycle(s.metric, s.tags, s.value)
+	}
+}
+
+// submitted reports the latency of the call sending the transaction with the
+// given hash and starts following it, sent is when the call started.
+func (c *Client) submitted(hash ethgo.Hash, txType string, sent time.Time) {
+	tags := metrics.NewRegistry().RootTagSet()
+	if c.vu != nil && c.vu.State() != nil {
+		tags = c.vu.State().Tags.GetCurrentValues().Tags
+	}
+	tags = tags.With("tx_type", txType)
+
+	c.reportLifecycle(c.metrics.SubmitDuration, tags, time.Since(sent))
+	if pt := c.propagation.Load(); pt != nil {
+		pt.track(hash, sent)
+	}
+
+	w := c.txWatcher()
+	if w == nil {
+		return
+	}
+	w.add(hash, &txLifecycle{
+		client:        c,
+		sent:          sent,
+		tags:          tags,
+		confirmations: uint64(c.opts.Confirmations),
+		finality:      c.opts.Finality,
+	})
+}
+
+// txWatcher returns the watcher of the block monitor of the client url. It's
+// nil if the block monitor is disabled, as transactions are only found in the
+// blocks it fetches.
+func (c *Client) txWatcher() *txWatcher {
+	if c.monitor == nil {
+		return nil
+	}
+
+	return c.monitor.txs
+}
+
+// sinceSent returns the time elapsed since the transaction was sent by the
+// client, or since start if it wasn't.
+func (c *Client) sinceSent(hash string, start time.Time) time.Duration {
+	if w := c.txWatcher(); w != nil {
+		if sent, ok := w.sent(ethgo.HexToHash(hash)); ok {
+			return time.Since(sent)
+		}
+	}
+
+	return time.Since(start)
+}
+
+func (c *Client) reportLifecycle(metric *metrics.Metric, tags *metrics.TagSet, t time.Duration) {
+	// If we are testing vu is nil
+	if c.vu == nil || c.vu.State() == nil {
+		return
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), c.vu.State().Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: metric,
+			Tags:   tags,
+		},
+		Value: float64(t / time.Millisecond),
+		Time:  time.Now(),
+	})
+}
+
+// txTypeName returns the name of the transaction type used in the tx_type tag.
+func txTypeName(typ ethgo.TransactionType) string {
+	switch typ {
+	case ethgo.TransactionAccessList:
+		return "access_list"
+	case ethgo.TransactionDynamicFee:
+		return "dynamic_fee"
+	default:
+		return "legacy"
+	}
+}
@@ -0,0 +1,124 @@
+package ethereum
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"go.k6.io/k6/metrics"
+)
+
+// fakeChain is a node answering the block requests of the block monitor.
+type fakeChain struct {
+	*testNode
+	head      uint64
+	safe      uint64
+	finalized uint64
+	txs       map[uint64]ethgo.Hash
+	// fork is the first block of the current fork, blocks from it on have
+	// other hashes than the ones of the previous fork
+	fork   uint64
+	forkID byte
+	// baseFee makes the blocks eip-1559 ones half full
+	baseFee uint64
+}
+
+func newFakeChain(t *testing.T, head uint64) *fakeChain {
+	f := &fakeChain{testNode: newTestNode(t), head: head, txs: map[uint64]ethgo.Hash{}}
+
+	f.handle("eth_blockNumber", func(*rpcRequest) (string, error) {
+		return fmt.Sprintf(`"0x%x"`, f.head), nil
+	})
+	f.handle("eth_getBlockByNumber", func(req *rpcRequest) (string, error) {
+		switch tag := req.params[0].(string); tag {
+		case "safe":
+			return f.block(f.safe), nil
+		case "finalized":
+			return f.block(f.finalized), nil
+		default:
+			var n uint64
+			fmt.Sscanf(tag, "0x%x", &n)
+			return f.block(n), nil
+		}
+	})
+	f.handle("eth_feeHistory", func(req *rpcRequest) (string, error) {
+		var count, newest uint64
+		fmt.Sscanf(req.params[0].(string), "0x%x", &count)
+		fmt.Sscanf(req.params[1].(string), "0x%x", &newest)
+		rewards := make([]string, count)
+		for i := range rewards {
+			rewards[i] = `["0x1","0x2","0x3"]`
+		}
+		return fmt.Sprintf(`{"oldestBlock":"0x%x","reward":[%s],"gasUsedRatio":[]}`, newest-count+1, strings.Join(rewards, ",")), nil
+	})
+
+	return f
+}
+
+func (f *fakeChain) hash(number uint64) ethgo.Hash {
+	h := ethgo.Hash{}
+	h[24] = byte(number >> 8)
+	h[25] = byte(number)
+	if f.fork > 0 && number >= f.fork {
+		h[0] = f.forkID
+	}
+	return h
+}
+
+func (f *fakeChain) block(number uint64) string {
+	hashes := "[]"
+	if h, ok := f.txs[number]; ok {
+		hashes = `["` + h.String() + `"]`
+	}
+	zero := ethgo.Hash{}.String()
+	gas := `"gasLimit":"0x0","gasUsed":"0x0"`
+	if f.baseFee > 0 {
+		gas = fmt.Sprintf(`"gasLimit":"0x64","gasUsed":"0x32","baseFeePerGas":"0x%x"`, f.baseFee)
+	}
+
+	return fmt.Sprintf(`{"number":"0x%x","hash":"%s","parentHash":"%s","sha3Uncles":"%s","transactionsRoot":"%s",`+
+		`"stateRoot":"%s","receiptsRoot":"%s","miner":"%s",%s,"timestamp":"0x%x",`+
+		`"difficulty":"0x0","extraData":"0x","transactions":%s,"uncles":[]}`,
+		number, f.hash(number), f.hash(number-1), zero, zero, zero, zero, ethgo.ZeroAddress.String(), gas, number*12, hashes)
+}
+
+func Test_txWatcher(t *testing.T) {
+	hash := ethgo.HexToHash("0x01")
+	chain := newFakeChain(t, 10)
+
+	// the watcher is fed by the blocks fetched by the monitor
+	bm := newBlockMonitor(nil, ethMetrics{}, newTestClient(t, chain.url).endpoints.primary(), monitorConfig{})
+	defer bm.stop()
+	require.NoError(t, bm.poll())
+
+	tags := metrics.NewRegistry().RootTagSet()
+	tx := &txLifecycle{client: &Client{}, sent: time.Now(), tags: tags, confirmations: 3, finality: true}
+	bm.txs.add(hash, tx)
+	_, ok := bm.txs.sent(hash)
+	require.True(t, ok)
+
+	// included in block 12
+	chain.set(func() {
+		chain.head = 12
+		chain.txs[12] = hash
+	})
+	require.NoError(t, bm.poll())
+	require.Equal(t, uint64(12), tx.block)
+	require.False(t, tx.confirmed)
+
+	chain.set(func() { chain.head = 14; chain.safe = 12 })
+	require.NoError(t, bm.poll())
+	require.True(t, tx.confirmed)
+	require.True(t, tx.safe)
+	require.False(t, tx.finalized)
+
+	chain.set(func() { chain.head = 15; chain.finalized = 12 })
+	require.NoError(t, bm.poll())
+	require.True(t, tx.finalized)
+
+	_, ok = bm.txs.sent(hash)
+	require.False(t, ok)
+}
@@ -3,17 +3,20 @@ package ethereum
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/grafana/sobek"
-	"github.com/umbracle/ethgo/jsonrpc"
+	"github.com/umbracle/ethgo"
 	"github.com/umbracle/ethgo/wallet"
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/lib/types"
 	"go.k6.io/k6/metrics"
 )
 
@@ -28,6 +31,28 @@ type ethMetrics struct {
 	GasUsed         *metrics.Metric
 	TPS             *metrics.Metric
 	BlockTime       *metrics.Metric
+	GasPerSecond    *metrics.Metric
+
+	BlockMonitorErrors *metrics.Metric
+	Reorg              *metrics.Metric
+	ReorgDepth         *metrics.Metric
+	Errors             *metrics.Metric
+	TxTimeout          *metrics.Metric
+	TxPropagation      *metrics.Metric
+	SubDropped         *metrics.Metric
+
+	SubmitDuration      *metrics.Metric
+	TimeToInclusion     *metrics.Metric
+	TimeToConfirmations *metrics.Metric
+	TimeToSafe          *metrics.Metric
+	TimeToFinalized     *metrics.Metric
+
+	BlobGasUsed *metrics.Metric
+	BlobBaseFee *metrics.Metric
+
+	BaseFee        *metrics.Metric
+	PriorityFee    *metrics.Metric
+	GasUtilization *metrics.Metric
 }
 
 func init() {
@@ -35,25 +60,33 @@ func init() {
 }
 
 // EthRoot is the root module
-type EthRoot struct{}
+type EthRoot struct {
+	// keystores are decrypted once for all the VUs
+	keystores keystoreCache
+}
 
 // NewModuleInstance implements the modules.Module interface returning a new instance for each VU.
-func (*EthRoot) NewModuleInstance(vu modules.VU) modules.Instance {
+func (r *EthRoot) NewModuleInstance(vu modules.VU) modules.Instance {
 	return &ModuleInstance{
-		vu: vu,
-		m:  registerMetrics(vu),
+		vu:   vu,
+		m:    registerMetrics(vu),
+		root: r,
 	}
 }
 
 type ModuleInstance struct {
-	vu modules.VU
-	m  ethMetrics
+	vu   modules.VU
+	m    ethMetrics
+	root *EthRoot
 }
 
 // Exports implements the modules.Instance interface and returns the exported types for the JS module.
 func (mi *ModuleInstance) Exports() modules.Exports {
 	return modules.Exports{Named: map[string]interface{}{
-		"Client": mi.NewClient,
+		"Client":             mi.NewClient,
+		"PropagationTracker": mi.NewPropagationTracker,
+		"fundAccounts":       mi.FundAccounts,
+		"sweep":              mi.Sweep,
 	}}
 }
 
@@ -71,21 +104,46 @@ func (mi *ModuleInstance) NewClient(call sobek.ConstructorCall) *sobek.Object {
 		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
 	}
 
-	if opts.URL == "" {
-		opts.URL = "http://localhost:8545"
+	if len(opts.URLs) == 0 {
+		if opts.URL == "" {
+			opts.URL = "http://localhost:8545"
+		}
+		opts.URLs = []string{opts.URL}
+	} else if opts.URL != "" {
+		common.Throw(rt, errors.New("invalid options; reason: only one of url or urls can be set"))
 	}
+	opts.URL = opts.URLs[0]
 
-	if opts.PrivateKey == "" {
+	if opts.Keystore != "" && (opts.Mnemonic != "" || opts.PrivateKey != "") {
+		common.Throw(rt, errors.New("invalid options; reason: only one of mnemonic, privateKey or keystore can be set"))
+	}
+	if opts.PrivateKey == "" && opts.Keystore == "" {
 		opts.PrivateKey = privateKey
 	}
 
-	var wa *wallet.Key
+	if opts.AccountCount < 0 {
+		common.Throw(rt, errors.New("invalid options; reason: accountCount can't be negative"))
+	}
+	if opts.Mnemonic == "" && (opts.AccountCount > 1 || opts.DerivationPath != "") {
+		common.Throw(rt, errors.New("invalid options; reason: accountCount and derivationPath require a mnemonic"))
+	}
+
+	var keys []*wallet.Key
 	if opts.Mnemonic != "" {
-		w, err := wallet.NewWalletFromMnemonic(opts.Mnemonic)
+		count := opts.AccountCount
+		if count == 0 {
+			count = 1
+		}
+		var err error
+		if keys, err = deriveKeys(opts.Mnemonic, opts.DerivationPath, count); err != nil {
+			common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+		}
+	} else if opts.Keystore != "" {
+		w, err := mi.root.keystores.decrypt([]byte(opts.Keystore), opts.Password)
 		if err != nil {
 			common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
 		}
-		wa = w
+		keys = []*wallet.Key{w}
 	} else if opts.PrivateKey != "" {
 		pk, err := hex.DecodeString(opts.PrivateKey)
 		if err != nil {
@@ -95,29 +153,111 @@ func (mi *ModuleInstance) NewClient(call sobek.ConstructorCall) *sobek.Object {
 		if err != nil {
 			common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
 		}
-		wa = w
+		keys = []*wallet.Key{w}
 	}
 
-	c, err := jsonrpc.NewClient(opts.URL)
+	if opts.Confirmations < 0 {
+		common.Throw(rt, errors.New("invalid options; reason: confirmations can't be negative"))
+	}
+
+	if err := opts.Fees.validate(); err != nil {
+		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+	}
+
+	if err := opts.Auth.validate(); err != nil {
+		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+	}
+
+	headers, err := opts.requestHeaders()
 	if err != nil {
 		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
 	}
 
-	cid, err := c.Eth().ChainID()
+	endpoints := make([]*endpoint, len(opts.URLs))
+	for i, url := range opts.URLs {
+		if endpoints[i], err = newEndpoint(url, opts); err != nil {
+			common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+		}
+	}
+
+	pool, err := newEndpointPool(opts.Strategy, endpoints)
 	if err != nil {
 		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
 	}
 
 	client := &Client{
-		vu:      mi.vu,
-		metrics: mi.m,
-		client:  c,
-		w:       wa,
-		chainID: cid,
-		opts:    opts,
+		vu:        mi.vu,
+		metrics:   mi.m,
+		endpoints: pool,
+		opts:      opts,
+		subs:      map[string]*subscription{},
+	}
+
+	accounts := make([]*account, len(keys))
+	for i, key := range keys {
+		accounts[i] = &account{key: key}
+		if opts.NonceManager {
+			address := key.Address().String()
+			accounts[i].nonces = newNonceManager(func() (uint64, error) {
+				return client.GetNonce(address)
+			})
+		}
+	}
+	if len(keys) > 0 {
+		client.w = keys[0]
+	}
+	if client.accounts, err = newAccountPool(opts.AccountStrategy, accounts); err != nil {
+		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+	}
+
+	cid, err := rpcCall(client, "eth_chainId", func(ctx context.Context, e *endpoint) (*big.Int, error) {
+		var id ethgo.ArgBig
+		err := e.call(ctx, "eth_chainId", &id)
+		return (*big.Int)(&id), err
+	})
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
 	}
+	client.chainID = cid
 
-	go client.pollForBlocks()
+	if opts.TrustedSetup != "" {
+		setup, err := loadKZGSetup(opts.TrustedSetup)
+		if err != nil {
+			common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+		}
+		client.kzg = setup
+	}
+
+	if isWebsocketURL(opts.URL) {
+		ws, err := dialWebsocket(opts.URL, headers)
+		if err != nil {
+			common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+		}
+		client.ws = ws
+
+		// closed with the VU, clients created on every iteration would leave
+		// their connections open otherwise
+		go func() {
+			select {
+			case <-mi.vu.Context().Done():
+				ws.close()
+			case <-ws.done:
+			}
+		}()
+	}
+
+	if opts.BlockMonitor == nil || *opts.BlockMonitor {
+		cfg := monitorConfig{
+			interval: time.Duration(opts.BlockMonitorInterval),
+			window:   opts.BlockMonitorWindow,
+		}
+		cfg.batch = opts.BlockMonitorBatch && !isWebsocketURL(opts.URL)
+		client.monitor = startBlockMonitor(mi.vu, mi.m, pool.primary(), cfg)
+	}
+
+	if opts.Fees != nil {
+		client.fees = newFeeOracle(client, *opts.Fees)
+	}
 
 	return rt.ToValue(client).ToObject(rt)
 }
@@ -131,17 +271,44 @@ func registerMetrics(vu modules.VU) ethMetrics {
 		GasUsed:         registry.MustNewMetric("ethereum_gas_used", metrics.Trend, metrics.Default),
 		TPS:             registry.MustNewMetric("ethereum_tps", metrics.Trend, metrics.Default),
 		BlockTime:       registry.MustNewMetric("ethereum_block_time", metrics.Trend, metrics.Time),
+		GasPerSecond:    registry.MustNewMetric("ethereum_gas_per_second", metrics.Trend, metrics.Default),
+
+		BlockMonitorErrors: registry.MustNewMetric("ethereum_block_monitor_errors", metrics.Counter, metrics.Default),
+		Reorg:              registry.MustNewMetric("ethereum_reorg", metrics.Counter, metrics.Default),
+		ReorgDepth:         registry.MustNewMetric("ethereum_reorg_depth", metrics.Trend, metrics.Default),
+		Errors:             registry.MustNewMetric("ethereum_errors", metrics.Counter, metrics.Default),
+		TxTimeout:          registry.MustNewMetric("ethereum_tx_timeout", metrics.Counter, metrics.Default),
+		TxPropagation:      registry.MustNewMetric("ethereum_tx_propagation", metrics.Trend, metrics.Time),
+		SubDropped:         registry.MustNewMetric("ethereum_subscription_dropped", metrics.Counter, metrics.Default),
+
+		SubmitDuration:      registry.MustNewMetric("ethereum_submit_duration", metrics.Trend, metrics.Time),
+		TimeToInclusion:     registry.MustNewMetric("ethereum_time_to_inclusion", metrics.Trend, metrics.Time),
+		TimeToConfirmations: registry.MustNewMetric("ethereum_time_to_confirmations", metrics.Trend, metrics.Time),
+		TimeToSafe:          registry.MustNewMetric("ethereum_time_to_safe", metrics.Trend, metrics.Time),
+		TimeToFinalized:     registry.MustNewMetric("ethereum_time_to_finalized", metrics.Trend, metrics.Time),
+
+		BlobGasUsed: registry.MustNewMetric("ethereum_blob_gas_used", metrics.Trend, metrics.Default),
+		BlobBaseFee: registry.MustNewMetric("ethereum_blob_base_fee", metrics.Trend, metrics.Default),
+
+		BaseFee:        registry.MustNewMetric("ethereum_base_fee", metrics.Trend, metrics.Default),
+		PriorityFee:    registry.MustNewMetric("ethereum_priority_fee", metrics.Trend, metrics.Default),
+		GasUtilization: registry.MustNewMetric("ethereum_gas_utilization", metrics.Trend, metrics.Default),
 	}
 
 	return m
 }
 
-func (c *Client) reportMetricsFromStats(call string, t time.Duration) {
+func (c *Client) reportMetricsFromStats(call, endpoint string, tags map[string]string, t time.Duration) {
+	// If we are testing vu is nil
+	if c.vu == nil || c.vu.State() == nil {
+		return
+	}
+
 	registry := metrics.NewRegistry()
 	metrics.PushIfNotDone(c.vu.Context(), c.vu.State().Samples, metrics.Sample{
 		TimeSeries: metrics.TimeSeries{
 			Metric: c.metrics.RequestDuration,
-			Tags:   registry.RootTagSet().With("call", call),
+			Tags:   registry.RootTagSet().WithTagsFromMap(tags).With("call", call).With("endpoint", endpoint),
 		},
 		Value: float64(t / time.Millisecond),
 		Time:  time.Now(),
@@ -150,17 +317,79 @@ func (c *Client) reportMetricsFromStats(call string, t time.Duration) {
 
 // options defines configuration options for the client.
 type options struct {
-	URL        string `json:"url,omitempty"`
+	URL string `json:"url,omitempty"`
+	// URLs are the endpoints of a cluster of nodes, calls are spread among
+	// them according to Strategy.
+	URLs []string `json:"urls,omitempty"`
+	// Strategy picks the endpoint of every call, one of round-robin, random,
+	// failover or least-latency. Defaults to round-robin.
+	Strategy   string `json:"strategy,omitempty"`
 	Mnemonic   string `json:"mnemonic,omitempty"`
 	PrivateKey string `json:"privateKey,omitempty"`
+	// Keystore is an encrypted key in the Web3 Secret Storage format, as
+	// written by geth or clef, decrypted with Password.
+	Keystore string `json:"keystore,omitempty"`
+	Password string `json:"password,omitempty"`
+	// AccountCount is the number of accounts derived from the mnemonic, defaults to 1.
+	AccountCount int `json:"accountCount,omitempty"`
+	// DerivationPath is the path the index of every account is appended to,
+	// defaults to m/44'/60'/0'/0.
+	DerivationPath string `json:"derivationPath,omitempty"`
+	// AccountStrategy picks the account of raw transactions without from,
+	// first or round-robin. Defaults to first.
+	AccountStrategy string `json:"accountStrategy,omitempty"`
+	// NonceManager enables tracking the nonce of every account locally when sending raw transactions.
+	NonceManager bool `json:"nonceManager,omitempty"`
+	// BlockMonitor enables the block monitor shared by all clients of the same url, defaults to true.
+	BlockMonitor *bool `json:"blockMonitor,omitempty"`
+	// BlockMonitorInterval is the polling interval of the block monitor, defaults to 500ms.
+	BlockMonitorInterval types.Duration `json:"blockMonitorInterval,omitempty"`
+	// BlockMonitorWindow is the number of blocks TPS and gas throughput are
+	// computed over, defaults to 10.
+	BlockMonitorWindow int `json:"blockMonitorWindow,omitempty"`
+	// BlockMonitorBatch makes the block monitor fetch the new blocks of every
+	// poll in a single batch request, for http urls.
+	BlockMonitorBatch bool `json:"blockMonitorBatch,omitempty"`
+	// TrustedSetup is the KZG trusted setup used to send blob transactions, as
+	// a trusted_setup.txt file or a json file with the g1_lagrange points.
+	TrustedSetup string `json:"trustedSetup,omitempty"`
+	// Headers are sent with every request to the node.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Auth is the basic, bearer or JWT authentication required by the node.
+	Auth *authOptions `json:"auth,omitempty"`
+	// Timeout of every RPC call, no timeout if not set.
+	Timeout types.Duration `json:"timeout,omitempty"`
+	// Retries is the number of times a call failing to reach the node or
+	// timing out is retried.
+	Retries int `json:"retries,omitempty"`
+	// Backoff is the delay before the first retry, doubled on each retry. Defaults to 100ms.
+	Backoff types.Duration `json:"backoff,omitempty"`
+	// Confirmations is the number of blocks reported in the time to
+	// confirmations of the transactions sent, not reported if not set.
+	Confirmations int `json:"confirmations,omitempty"`
+	// Finality enables reporting the time for transactions sent to be in the
+	// safe and finalized blocks.
+	Finality bool `json:"finality,omitempty"`
+	// Fees enables filling the fees of the transactions sent without any.
+	Fees *feeOptions `json:"fees,omitempty"`
 }
 
 // newOptionsFrom validates and instantiates an options struct from its map representation
 // as obtained by calling a Goja's Runtime.ExportTo.
 func newOptionsFrom(argument map[string]interface{}) (*options, error) {
+	var opts options
+	if err := decodeOptions(argument, &opts); err != nil {
+		return nil, err
+	}
+
+	return &opts, nil
+}
+
+// decodeOptions decodes the map representation of an options object into out.
+func decodeOptions(argument map[string]interface{}, out interface{}) error {
 	jsonStr, err := json.Marshal(argument)
 	if err != nil {
-		return nil, fmt.Errorf("unable to serialize options to JSON %w", err)
+		return fmt.Errorf("unable to serialize options to JSON %w", err)
 	}
 
 	// Instantiate a JSON decoder which will error on unknown
@@ -169,11 +398,9 @@ func newOptionsFrom(argument map[string]interface{}) (*options, error) {
 	decoder := json.NewDecoder(bytes.NewReader(jsonStr))
 	decoder.DisallowUnknownFields()
 
-	var opts options
-	err = decoder.Decode(&opts)
-	if err != nil {
-		return nil, fmt.Errorf("unable to decode options %w", err)
+	if err := decoder.Decode(out); err != nil {
+		return fmt.Errorf("unable to decode options %w", err)
 	}
 
-	return &opts, nil
+	return nil
 }
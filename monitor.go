@@ -0,0 +1,697 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/jsonrpc"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+	"go.k6.io/k6/event"
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	defaultBlockMonitorInterval = 500 * time.Millisecond
+	defaultBlockMonitorWindow   = 10
+	// reorgWindow is the number of recent block hashes kept to detect reorgs.
+	reorgWindow = 64
+	// maxBlocksPerPoll bounds the blocks fetched on a single poll.
+	maxBlocksPerPoll = 128
+)
+
+// priorityFeePercentiles are the percentiles of the effective priority fees
+// of every block requested to eth_feeHistory.
+var priorityFeePercentiles = []float64{10, 50, 90}
+
+// monitors holds the running block monitors by endpoint url.
+var monitors sync.Map
+
+// blockMonitor polls an endpoint for new blocks and emits the block metrics.
+// A single monitor is shared by all the clients of every VU pointing to the
+// same url, and it is stopped when the test ends.
+type blockMonitor struct {
+	url        string
+	endpoint   *endpoint
+	metrics    ethMetrics
+	interval   time.Duration
+	windowSize int
+	// batch fetches the new blocks in a batch request instead of one by one
+	batch bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// vus are the VUs of the clients sharing the monitor. Samples are pushed
+	// through one that is running, as the first client is usually created by
+	// a VU only running the init context.
+	vusLock sync.Mutex
+	vus     map[modules.VU]struct{}
+	running modules.VU
+
+	lastBlockNumber uint64
+	// latest is lastBlockNumber read by other goroutines
+	latest   uint64
+	lastSeen time.Time
+	// window holds the last blocks TPS and gas throughput are computed over
+	window []*ethgo.Block
+	// hashes are the hashes of the recent blocks by number
+	hashes map[uint64]ethgo.Hash
+	// london is set once a block with a base fee is seen, priority fees are
+	// only requested to chains with eip-1559 blocks
+	london bool
+	// txs follows the transactions sent to the url through the blocks fetched
+	txs *txWatcher
+}
+
+// monitorConfig configures a block monitor.
+type monitorConfig struct {
+	interval time.Duration
+	// window is the number of blocks TPS and gas throughput are computed over
+	window int
+	// batch enables fetching the new blocks in a batch request, for http urls
+	batch bool
+}
+
+func newBlockMonitor(vu modules.VU, m ethMetrics, e *endpoint, cfg monitorConfig) *blockMonitor {
+	if cfg.interval <= 0 {
+		cfg.interval = defaultBlockMonitorInterval
+	}
+	if cfg.window < 2 {
+		cfg.window = defaultBlockMonitorWindow
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	bm := &blockMonitor{
+		url:        e.url,
+		endpoint:   e,
+		metrics:    m,
+		interval:   cfg.interval,
+		windowSize: cfg.window,
+		batch:      cfg.batch,
+		ctx:        ctx,
+		cancel:     cancel,
+		lastSeen:   time.Now(),
+		hashes:     map[uint64]ethgo.Hash{},
+		txs:        newTxWatcher(),
+		vus:        map[modules.VU]struct{}{},
+	}
+	bm.attach(vu)
+
+	return bm
+}
+
+// startBlockMonitor returns the monitor for the url of e, starting it if no
+// other client did it before, and adds vu to the VUs it pushes samples through.
+// The monitor keeps the config of the client starting it, the one of later
+// clients of the same url is ignored.
+func startBlockMonitor(vu modules.VU, m ethMetrics, e *endpoint, cfg monitorConfig) *blockMonitor {
+	if v, ok := monitors.Load(e.url); ok {
+		bm := v.(*blockMonitor)
+		bm.attach(vu)
+		return bm
+	}
+
+	bm := newBlockMonitor(vu, m, e, cfg)
+	if v, loaded := monitors.LoadOrStore(e.url, bm); loaded {
+		bm := v.(*blockMonitor)
+		bm.attach(vu)
+		return bm
+	}
+
+	if vu != nil && vu.Events().Global != nil {
+		events := vu.Events().Global
+		subID, ch := events.Subscribe(event.TestEnd, event.Exit)
+		go func() {
+			defer events.Unsubscribe(subID)
+			select {
+			case ev := <-ch:
+				bm.stop()
+				ev.Done()
+			case <-bm.ctx.Done():
+			}
+		}()
+	}
+
+	go bm.run()
+
+	return bm
+}
+
+// stop stops polling and releases the url so a new monitor can be started.
+func (bm *blockMonitor) stop() {
+	bm.cancel()
+	monitors.CompareAndDelete(bm.url, bm)
+}
+
+func (bm *blockMonitor) run() {
+	ticker := time.NewTicker(bm.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bm.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := bm.poll(); err != nil {
+				bm.reportError()
+			}
+		}
+	}
+}
+
+// poll fetches the blocks since the last one seen up to the latest one and
+// emits their metrics.
+func (bm *blockMonitor) poll() error {
+	head, err := bm.endpoint.blockNumber(bm.ctx)
+	if err != nil {
+		return err
+	}
+
+	if head <= bm.lastBlockNumber {
+		return nil
+	}
+
+	from := bm.lastBlockNumber + 1
+	if bm.lastBlockNumber == 0 || head-from >= maxBlocksPerPoll {
+		from = head
+	}
+
+	raws, err := bm.fetchBlocks(from, head)
+	if err != nil {
+		return err
+	}
+
+	// the time since the last poll is spread among the new blocks
+	blockTime := time.Since(bm.lastSeen) / time.Duration(len(raws))
+	bm.lastSeen = time.Now()
+
+	for _, raw := range raws {
+		if len(raw) == 0 || string(raw) == "null" {
+			// We're not going to continue past this point if we don't have a block
+			return nil
+		}
+		block := new(ethgo.Block)
+		if err := block.UnmarshalJSON(raw); err != nil {
+			return err
+		}
+		if err := bm.process(block, raw, blockTime, block.Number == head); err != nil {
+			return err
+		}
+	}
+
+	if err := bm.followTxs(head); err != nil {
+		return err
+	}
+
+	if !bm.london {
+		return nil
+	}
+
+	samples, err := bm.priorityFeeSamples(uint64(len(raws)), head)
+	if err != nil {
+		return err
+	}
+	bm.push(metrics.ConnectedSamples{Samples: samples})
+
+	return nil
+}
+
+// fetchBlocks returns the raw blocks from the from to the to numbers, in a
+// single batch request if enabled.
+func (bm *blockMonitor) fetchBlocks(from, to uint64) ([]json.RawMessage, error) {
+	raws := make([]json.RawMessage, 0, to-from+1)
+
+	if !bm.batch || from == to {
+		for n := from; n <= to; n++ {
+			var raw json.RawMessage
+			if err := bm.endpoint.call(bm.ctx, "eth_getBlockByNumber", &raw, ethgo.BlockNumber(n).String(), false); err != nil {
+				return nil, err
+			}
+			raws = append(raws, raw)
+		}
+		return raws, nil
+	}
+
+	batch := make([]codec.Request, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		params, _ := json.Marshal([]interface{}{ethgo.BlockNumber(n).String(), false})
+		batch = append(batch, codec.Request{JsonRPC: "2.0", ID: n - from, Method: "eth_getBlockByNumber", Params: params})
+	}
+
+	responses, err := bm.endpoint.postBatch(bm.ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	raws = raws[:len(batch)]
+	for _, resp := range responses {
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		if resp.ID < uint64(len(raws)) {
+			raws[resp.ID] = resp.Result
+		}
+	}
+
+	return raws, nil
+}
+
+// process emits the metrics of a new block, blockTime is the time it took
+// to be seen since the previous one.
+func (bm *blockMonitor) process(block *ethgo.Block, raw json.RawMessage, blockTime time.Duration, latest bool) error {
+	blobSamples, err := bm.blobSamples(raw, block.Number, latest)
+	if err != nil {
+		return err
+	}
+	feeSamples, err := bm.feeSamples(raw, block)
+	if err != nil {
+		return err
+	}
+
+	depth, err := bm.link(block)
+	if err != nil {
+		return err
+	}
+	pushLifecycle(bm.txs.included(block))
+	bm.lastBlockNumber = block.Number
+	atomic.StoreUint64(&bm.latest, block.Number)
+
+	// orphaned blocks are dropped from the window
+	for len(bm.window) > 0 && bm.window[len(bm.window)-1].Number+depth >= block.Number {
+		bm.window = bm.window[:len(bm.window)-1]
+	}
+
+	var blockTimestampDiff time.Duration
+	if len(bm.window) > 0 {
+		prev := bm.window[len(bm.window)-1]
+		blockTimestampDiff = time.Unix(int64(block.Timestamp), 0).Sub(time.Unix(int64(prev.Timestamp), 0))
+	}
+
+	bm.window = append(bm.window, block)
+	if len(bm.window) > bm.windowSize {
+		bm.window = bm.window[len(bm.window)-bm.windowSize:]
+	}
+
+	rootTS := metrics.NewRegistry().RootTagSet()
+	samples := []metrics.Sample{
+		{
+			TimeSeries: metrics.TimeSeries{
+				Metric: bm.metrics.Block,
+				Tags: rootTS.WithTagsFromMap(map[string]string{
+					"transactions": strconv.Itoa(len(block.TransactionsHashes)),
+					"gas_used":     strconv.Itoa(int(block.GasUsed)),
+					"gas_limit":    strconv.Itoa(int(block.GasLimit)),
+				}),
+			},
+			Value: float64(block.Number),
+			Time:  time.Now(),
+		},
+		{
+			TimeSeries: metrics.TimeSeries{
+				Metric: bm.metrics.GasUsed,
+				Tags:   rootTS,
+			},
+			Metadata: blockMetadata(block.Number),
+			Value:    float64(block.GasUsed),
+			Time:     time.Now(),
+		},
+		{
+			TimeSeries: metrics.TimeSeries{
+				Metric: bm.metrics.BlockTime,
+				Tags: rootTS.WithTagsFromMap(map[string]string{
+					"block_timestamp_diff": blockTimestampDiff.String(),
+				}),
+			},
+			Value: float64(blockTime.Milliseconds()),
+			Time:  time.Now(),
+		},
+	}
+
+	if tps, gasPerSecond, ok := bm.throughput(); ok {
+		samples = append(samples,
+			metrics.Sample{
+				TimeSeries: metrics.TimeSeries{
+					Metric: bm.metrics.TPS,
+					Tags:   rootTS,
+				},
+				Value: tps,
+				Time:  time.Now(),
+			},
+			metrics.Sample{
+				TimeSeries: metrics.TimeSeries{
+					Metric: bm.metrics.GasPerSecond,
+					Tags:   rootTS,
+				},
+				Value: gasPerSecond,
+				Time:  time.Now(),
+			},
+		)
+	}
+
+	bm.push(metrics.ConnectedSamples{Samples: append(samples, feeSamples...)})
+
+	if len(blobSamples) > 0 {
+		bm.push(metrics.ConnectedSamples{Samples: blobSamples})
+	}
+
+	return nil
+}
+
+// followTxs reports the confirmations and finality of the transactions
+// followed, the safe and finalized blocks are only fetched when awaited.
+func (bm *blockMonitor) followTxs(head uint64) error {
+	// confirmations are still reported by nodes without safe and finalized tags
+	safe, finalized, err := bm.txs.finality(bm.ctx, bm.endpoint)
+	pushLifecycle(bm.txs.advance(head, safe, finalized))
+
+	return err
+}
+
+// head returns the number of the last block seen, zero until the first poll.
+func (bm *blockMonitor) head() uint64 {
+	return atomic.LoadUint64(&bm.latest)
+}
+
+// throughput returns the transactions and gas per second of the blocks in the
+// window, false until the window spans more than a second.
+func (bm *blockMonitor) throughput() (float64, float64, bool) {
+	if len(bm.window) < 2 {
+		return 0, 0, false
+	}
+
+	first, last := bm.window[0], bm.window[len(bm.window)-1]
+	if last.Timestamp <= first.Timestamp {
+		return 0, 0, false
+	}
+	seconds := float64(last.Timestamp - first.Timestamp)
+
+	// the first block was produced before the window started
+	var txs, gas uint64
+	for _, b := range bm.window[1:] {
+		txs += uint64(len(b.TransactionsHashes))
+		gas += b.GasUsed
+	}
+
+	return float64(txs) / seconds, float64(gas) / seconds, true
+}
+
+// link adds block to the recent blocks. If its parent hash doesn't match the
+// block known at its height, the ancestors are fetched until one does to
+// report the depth of the reorg, which is returned, and to find the
+// transactions of the orphaned blocks again.
+func (bm *blockMonitor) link(block *ethgo.Block) (uint64, error) {
+	depth := uint64(0)
+	var ancestors []*ethgo.Block
+	for b := block; b.Number > 0; {
+		known, ok := bm.hashes[b.Number-1]
+		if !ok || known == b.ParentHash {
+			break
+		}
+		depth++
+
+		canonical, err := bm.endpoint.blockByNumber(bm.ctx, ethgo.BlockNumber(b.Number-1), false)
+		if err != nil {
+			return 0, err
+		}
+		if canonical == nil {
+			break
+		}
+		bm.hashes[canonical.Number] = canonical.Hash
+		ancestors = append(ancestors, canonical)
+		b = canonical
+	}
+
+	bm.hashes[block.Number] = block.Hash
+	for n := range bm.hashes {
+		if n+reorgWindow <= block.Number || n > block.Number {
+			delete(bm.hashes, n)
+		}
+	}
+
+	if depth > 0 {
+		bm.reportReorg(depth)
+		bm.txs.reorg(block.Number - depth)
+		var samples []lifecycleSample
+		for i := len(ancestors) - 1; i >= 0; i-- {
+			samples = append(samples, bm.txs.included(ancestors[i])...)
+		}
+		pushLifecycle(samples)
+	}
+
+	return depth, nil
+}
+
+func (bm *blockMonitor) reportReorg(depth uint64) {
+	tags := metrics.NewRegistry().RootTagSet().With("url", bm.url)
+	bm.push(metrics.ConnectedSamples{
+		Samples: []metrics.Sample{
+			{
+				TimeSeries: metrics.TimeSeries{
+					Metric: bm.metrics.Reorg,
+					Tags:   tags,
+				},
+				Value: 1,
+				Time:  time.Now(),
+			},
+			{
+				TimeSeries: metrics.TimeSeries{
+					Metric: bm.metrics.ReorgDepth,
+					Tags:   tags,
+				},
+				Value: float64(depth),
+				Time:  time.Now(),
+			},
+		},
+	})
+}
+
+// feeSamples returns the gas utilization of the block and its base fee, for
+// eip-1559 blocks.
+func (bm *blockMonitor) feeSamples(raw json.RawMessage, block *ethgo.Block) ([]metrics.Sample, error) {
+	var fields struct {
+		BaseFeePerGas *string `json:"baseFeePerGas"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	tags := metrics.NewRegistry().RootTagSet()
+	metadata := blockMetadata(block.Number)
+	var samples []metrics.Sample
+	if block.GasLimit > 0 {
+		samples = append(samples, metrics.Sample{
+			TimeSeries: metrics.TimeSeries{
+				Metric: bm.metrics.GasUtilization,
+				Tags:   tags,
+			},
+			Metadata: metadata,
+			Value:    float64(block.GasUsed) / float64(block.GasLimit),
+			Time:     time.Now(),
+		})
+	}
+	if fields.BaseFeePerGas == nil {
+		return samples, nil
+	}
+
+	baseFee, err := Wei(*fields.BaseFeePerGas).Int()
+	if err != nil {
+		return nil, err
+	}
+	fee, _ := new(big.Float).SetInt(baseFee).Float64()
+	bm.london = true
+
+	return append(samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: bm.metrics.BaseFee,
+			Tags:   tags,
+		},
+		Metadata: metadata,
+		Value:    fee,
+		Time:     time.Now(),
+	}), nil
+}
+
+// priorityFeeSamples returns the percentiles of the effective priority fees
+// paid in the count blocks up to head, tagged by percentile.
+func (bm *blockMonitor) priorityFeeSamples(count, head uint64) ([]metrics.Sample, error) {
+	var history *jsonrpc.FeeHistory
+	if err := bm.endpoint.call(bm.ctx, "eth_feeHistory", &history, ethgo.BlockNumber(count).String(), ethgo.BlockNumber(head).String(), priorityFeePercentiles); err != nil {
+		return nil, err
+	}
+	if history == nil || history.OldestBlock == nil {
+		return nil, nil
+	}
+
+	rootTS := metrics.NewRegistry().RootTagSet()
+	var samples []metrics.Sample
+	for i, rewards := range history.Reward {
+		metadata := blockMetadata(history.OldestBlock.Uint64() + uint64(i))
+		for j, reward := range rewards {
+			if j >= len(priorityFeePercentiles) || reward == nil {
+				break
+			}
+			fee, _ := new(big.Float).SetInt(reward).Float64()
+			samples = append(samples, metrics.Sample{
+				TimeSeries: metrics.TimeSeries{
+					Metric: bm.metrics.PriorityFee,
+					Tags:   rootTS.With("percentile", strconv.FormatFloat(priorityFeePercentiles[j], 'f', -1, 64)),
+				},
+				Metadata: metadata,
+				Value:    fee,
+				Time:     time.Now(),
+			})
+		}
+	}
+
+	return samples, nil
+}
+
+// blobSamples returns the blob gas used by the block and, for the latest one,
+// the blob base fee. Nothing for blocks before eip-4844.
+func (bm *blockMonitor) blobSamples(raw json.RawMessage, blockNumber uint64, latest bool) ([]metrics.Sample, error) {
+	var fields struct {
+		BlobGasUsed *string `json:"blobGasUsed"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	if fields.BlobGasUsed == nil {
+		return nil, nil
+	}
+
+	blobGasUsed, err := strconv.ParseUint(*fields.BlobGasUsed, 0, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := metrics.NewRegistry().RootTagSet()
+	metadata := blockMetadata(blockNumber)
+	samples := []metrics.Sample{
+		{
+			TimeSeries: metrics.TimeSeries{
+				Metric: bm.metrics.BlobGasUsed,
+				Tags:   tags,
+			},
+			Metadata: metadata,
+			Value:    float64(blobGasUsed),
+			Time:     time.Now(),
+		},
+	}
+	if !latest {
+		return samples, nil
+	}
+
+	var out string
+	if err := bm.endpoint.call(bm.ctx, "eth_blobBaseFee", &out); err != nil {
+		return nil, err
+	}
+	blobBaseFee, err := Wei(out).Int()
+	if err != nil {
+		return nil, err
+	}
+	fee, _ := new(big.Float).SetInt(blobBaseFee).Float64()
+
+	return append(samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: bm.metrics.BlobBaseFee,
+			Tags:   tags,
+		},
+		Metadata: metadata,
+		Value:    fee,
+		Time:     time.Now(),
+	}), nil
+}
+
+// blockMetadata returns the metadata of the samples of a block. The number is
+// not a tag, as every block would create new time series.
+func blockMetadata(number uint64) map[string]string {
+	return map[string]string{"block": strconv.FormatUint(number, 10)}
+}
+
+func (bm *blockMonitor) reportError() {
+	bm.push(metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: bm.metrics.BlockMonitorErrors,
+			Tags:   metrics.NewRegistry().RootTagSet().With("url", bm.url),
+		},
+		Value: 1,
+		Time:  time.Now(),
+	})
+}
+
+// attach adds vu to the VUs samples can be pushed through.
+func (bm *blockMonitor) attach(vu modules.VU) {
+	// If we are testing vu is nil
+	if vu == nil {
+		return
+	}
+
+	bm.vusLock.Lock()
+	_, attached := bm.vus[vu]
+	bm.vus[vu] = struct{}{}
+	bm.vusLock.Unlock()
+	if attached {
+		return
+	}
+
+	// dropped once done, as the samples of a VU done are never collected
+	ctx := vu.Context()
+	go func() {
+		select {
+		case <-ctx.Done():
+			bm.detach(vu)
+		case <-bm.ctx.Done():
+		}
+	}()
+}
+
+// detach removes vu from the VUs samples can be pushed through.
+func (bm *blockMonitor) detach(vu modules.VU) {
+	bm.vusLock.Lock()
+	defer bm.vusLock.Unlock()
+
+	delete(bm.vus, vu)
+	if bm.running == vu {
+		bm.running = nil
+	}
+}
+
+// state returns the state of a running VU, nil while none of them runs.
+func (bm *blockMonitor) state() *lib.State {
+	bm.vusLock.Lock()
+	defer bm.vusLock.Unlock()
+
+	if bm.running != nil {
+		if state := bm.running.State(); state != nil {
+			return state
+		}
+	}
+	for vu := range bm.vus {
+		if state := vu.State(); state != nil {
+			bm.running = vu
+			return state
+		}
+	}
+
+	return nil
+}
+
+// push sends samples through a running VU, they're dropped before the test
+// starts.
+func (bm *blockMonitor) push(samples metrics.SampleContainer) {
+	state := bm.state()
+	if state == nil {
+		return
+	}
+
+	metrics.PushIfNotDone(bm.ctx, state.Samples, samples)
+}
@@ -0,0 +1,201 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+)
+
+func Test_blockMonitorReorg(t *testing.T) {
+	chain := newFakeChain(t, 10)
+	e := newTestClient(t, chain.url).endpoints.primary()
+	bm := newBlockMonitor(nil, ethMetrics{}, e, monitorConfig{})
+	defer bm.stop()
+
+	// a transaction followed by the monitor, included in block 11
+	hash := ethgo.HexToHash("0x01")
+	tx := &txLifecycle{client: &Client{}, sent: time.Now(), tags: metrics.NewRegistry().RootTagSet(), block: 11}
+	bm.txs.add(hash, tx)
+
+	require.NoError(t, bm.poll())
+	chain.set(func() { chain.head = 13 })
+	require.NoError(t, bm.poll())
+	// the skipped block 11 and 12 are known
+	require.Equal(t, chain.hash(11), bm.hashes[11])
+	require.Equal(t, chain.hash(12), bm.hashes[12])
+
+	// blocks 12 and 13 are replaced and 14 is mined on top of them
+	chain.set(func() {
+		chain.head = 14
+		chain.fork = 12
+		chain.forkID = 1
+	})
+	require.NoError(t, bm.poll())
+	require.Equal(t, chain.hash(12), bm.hashes[12])
+	require.Equal(t, chain.hash(13), bm.hashes[13])
+	require.Equal(t, chain.hash(14), bm.window[len(bm.window)-1].Hash)
+	// the orphaned blocks 12 and 13 were dropped from the window
+	for _, b := range bm.window {
+		require.Equal(t, chain.hash(b.Number), b.Hash)
+	}
+
+	// block 11 was not orphaned by the reorg of depth 2
+	require.Equal(t, uint64(11), tx.block)
+
+	// blocks 11 to 14 are replaced, the transaction is in the new block 12
+	chain.set(func() {
+		chain.head = 15
+		chain.fork = 11
+		chain.forkID = 2
+		chain.txs[12] = hash
+	})
+	require.NoError(t, bm.poll())
+	// found again in the canonical ancestors fetched by the monitor
+	require.Equal(t, uint64(12), tx.block)
+	_, reorged := tx.tags.Get("reorged")
+	require.True(t, reorged)
+}
+
+func Test_blockMonitorThroughput(t *testing.T) {
+	chain := newFakeChain(t, 10)
+	for n := uint64(1); n <= 20; n++ {
+		chain.txs[n] = ethgo.HexToHash("0x01")
+	}
+	e := newTestClient(t, chain.url).endpoints.primary()
+	bm := newBlockMonitor(nil, ethMetrics{}, e, monitorConfig{window: 4, batch: true})
+	defer bm.stop()
+
+	require.NoError(t, bm.poll())
+	_, _, ok := bm.throughput()
+	require.False(t, ok)
+
+	// the 5 new blocks are fetched in a single batch
+	chain.set(func() { chain.head = 15 })
+	require.NoError(t, bm.poll())
+	require.Equal(t, 1, chain.batches)
+	require.Equal(t, uint64(15), bm.lastBlockNumber)
+
+	require.Len(t, bm.window, 4)
+	for i, b := range bm.window {
+		require.Equal(t, uint64(12+i), b.Number)
+	}
+
+	// a transaction every 12 seconds
+	tps, gasPerSecond, ok := bm.throughput()
+	require.True(t, ok)
+	require.InDelta(t, 1.0/12, tps, 1e-9)
+	require.Zero(t, gasPerSecond)
+}
+
+func Test_blockMonitorFees(t *testing.T) {
+	chain := newFakeChain(t, 10)
+	e := newTestClient(t, chain.url).endpoints.primary()
+	bm := newBlockMonitor(nil, ethMetrics{}, e, monitorConfig{})
+	defer bm.stop()
+
+	// no fees before eip-1559
+	require.NoError(t, bm.poll())
+	require.False(t, bm.london)
+
+	chain.set(func() {
+		chain.head = 12
+		chain.baseFee = 7
+	})
+	require.NoError(t, bm.poll())
+	require.True(t, bm.london)
+
+	block, err := e.blockByNumber(context.Background(), 12, false)
+	require.NoError(t, err)
+	var raw json.RawMessage
+	require.NoError(t, e.call(context.Background(), "eth_getBlockByNumber", &raw, "0xc", false))
+
+	samples, err := bm.feeSamples(raw, block)
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	require.Equal(t, 0.5, samples[0].Value)
+	require.Equal(t, float64(7), samples[1].Value)
+	for _, s := range samples {
+		_, tagged := s.Tags.Get("block")
+		require.False(t, tagged)
+		require.Equal(t, "12", s.Metadata["block"])
+	}
+
+	samples, err = bm.priorityFeeSamples(2, 12)
+	require.NoError(t, err)
+	require.Len(t, samples, 6)
+	for i, s := range samples {
+		require.Equal(t, float64(i%3+1), s.Value)
+		_, tagged := s.Tags.Get("block")
+		require.False(t, tagged)
+		require.Equal(t, strconv.Itoa(11+i/3), s.Metadata["block"])
+		percentile, _ := s.Tags.Get("percentile")
+		require.Equal(t, strconv.FormatFloat(priorityFeePercentiles[i%3], 'f', -1, 64), percentile)
+	}
+}
+
+func Test_blockMonitorInitClient(t *testing.T) {
+	chain := newFakeChain(t, 10)
+
+	// the monitor is started by the client of a VU only running the init
+	// context, the same as the one of every other VU
+	initVU, vu := newTestVU(t), newTestVU(t)
+	for _, v := range []*testVU{initVU, vu} {
+		mi := (&EthRoot{}).NewModuleInstance(v).(*ModuleInstance)
+		require.NoError(t, v.rt.Set("Client", mi.Exports().Named["Client"]))
+		_, err := v.rt.RunString(`new Client({url: "` + chain.url + `", blockMonitorInterval: "10ms"})`)
+		require.NoError(t, err)
+	}
+	v, ok := monitors.Load(chain.url)
+	require.True(t, ok)
+	defer v.(*blockMonitor).stop()
+
+	samples := make(chan metrics.SampleContainer, 1000)
+	vu.state.Store(&lib.State{Samples: samples})
+	chain.set(func() { chain.head = 11 })
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case container := <-samples:
+			for _, s := range container.GetSamples() {
+				if s.Metric.Name == "ethereum_block" {
+					return
+				}
+			}
+		case <-timeout:
+			t.Fatal("no block sample pushed through the running VU")
+		}
+	}
+}
+
+func Test_blockMonitorAttach(t *testing.T) {
+	bm := newBlockMonitor(nil, ethMetrics{}, &endpoint{url: "http://localhost"}, monitorConfig{})
+	defer bm.stop()
+
+	vu := newTestVU(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	vu.ctx = ctx
+	vu.state.Store(&lib.State{})
+
+	// a client per iteration attaches the same VU once
+	bm.attach(vu)
+	bm.attach(vu)
+	require.Len(t, bm.vus, 1)
+	require.NotNil(t, bm.state())
+
+	// detached once done
+	cancel()
+	require.Eventually(t, func() bool {
+		bm.vusLock.Lock()
+		defer bm.vusLock.Unlock()
+		return len(bm.vus) == 0 && bm.running == nil
+	}, 5*time.Second, time.Millisecond)
+	require.Nil(t, bm.state())
+}
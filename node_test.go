@@ -0,0 +1,246 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+	"github.com/umbracle/ethgo/wallet"
+)
+
+// errNoResponse is returned by the handler of a request the node never
+// answers. Over http the request is held until the client gives up on it.
+var errNoResponse = errors.New("no response")
+
+// rpcRequest is a request received by a testNode.
+type rpcRequest struct {
+	method string
+	params []interface{}
+	// notifications are sent over the websocket after the response, and the
+	// connection is closed after them if close is set
+	notifications []string
+	close         bool
+}
+
+// notify sends result as a notification of the subscription 0x1 after the
+// response.
+func (r *rpcRequest) notify(result string) {
+	r.notifications = append(r.notifications,
+		`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x1","result":`+result+`}}`)
+}
+
+// rpcHandler answers a request with its raw JSON result or an error, a
+// *codec.ErrorObject keeps its code. An empty result is left out of the
+// response.
+type rpcHandler func(req *rpcRequest) (string, error)
+
+// result returns a handler always answering raw.
+func result(raw string) rpcHandler {
+	return func(*rpcRequest) (string, error) { return raw, nil }
+}
+
+// testNode is a fake JSON-RPC node answering requests over http, in batches
+// and over websockets with the handler of their method. Methods without a
+// handler are answered with null. Handlers are called with the lock held, the
+// state they read is changed with set.
+type testNode struct {
+	url string
+
+	lock     sync.Mutex
+	handlers map[string]rpcHandler
+	// calls counts the requests of every method
+	calls map[string]int
+	// batches counts the batch requests
+	batches int
+	// headers are the headers of every http request
+	headers []http.Header
+	// abandoned counts the unanswered http requests the client gave up on
+	abandoned int
+}
+
+// newTestNode starts a node, stopped at the end of the test, with the chain
+// id 1337.
+func newTestNode(t *testing.T) *testNode {
+	n := &testNode{
+		handlers: map[string]rpcHandler{"eth_chainId": result(`"0x539"`)},
+		calls:    map[string]int{},
+	}
+
+	srv := httptest.NewServer(n)
+	t.Cleanup(srv.Close)
+	n.url = srv.URL
+
+	return n
+}
+
+func (n *testNode) wsURL() string {
+	return "ws" + strings.TrimPrefix(n.url, "http")
+}
+
+func (n *testNode) handle(method string, h rpcHandler) {
+	n.set(func() { n.handlers[method] = h })
+}
+
+func (n *testNode) set(fn func()) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	fn()
+}
+
+func (n *testNode) count(method string) int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.calls[method]
+}
+
+func (n *testNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		n.serveWebsocket(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n.set(func() { n.headers = append(n.headers, r.Header.Clone()) })
+
+	if len(body) > 0 && body[0] == '[' {
+		var batch []codec.Request
+		if err := json.Unmarshal(body, &batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n.set(func() { n.batches++ })
+
+		// answered out of order as nodes are allowed to
+		responses := make([]string, 0, len(batch))
+		for i := len(batch) - 1; i >= 0; i-- {
+			if resp, _ := n.answer(batch[i]); resp != "" {
+				responses = append(responses, resp)
+			}
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(responses, ","))
+		return
+	}
+
+	var req codec.Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, _ := n.answer(req)
+	if resp == "" {
+		select {
+		case <-r.Context().Done():
+			n.set(func() { n.abandoned++ })
+		case <-time.After(5 * time.Second):
+		}
+		return
+	}
+	fmt.Fprint(w, resp)
+}
+
+func (n *testNode) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for {
+		var req codec.Request
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+
+		resp, handled := n.answer(req)
+		if resp != "" {
+			conn.WriteMessage(websocket.TextMessage, []byte(resp))
+		}
+		for _, msg := range handled.notifications {
+			conn.WriteMessage(websocket.TextMessage, []byte(msg))
+		}
+		if handled.close {
+			return
+		}
+	}
+}
+
+// answer returns the response to req, empty if it isn't answered, and the
+// request as seen by its handler.
+func (n *testNode) answer(req codec.Request) (string, *rpcRequest) {
+	r := &rpcRequest{method: req.Method}
+	json.Unmarshal(req.Params, &r.params)
+
+	n.lock.Lock()
+	n.calls[req.Method]++
+	res, err := "null", error(nil)
+	if h, ok := n.handlers[req.Method]; ok {
+		res, err = h(r)
+	}
+	n.lock.Unlock()
+
+	if errors.Is(err, errNoResponse) {
+		return "", r
+	}
+	if err != nil {
+		obj := &codec.ErrorObject{Code: -32000, Message: err.Error()}
+		errors.As(err, &obj)
+		msg, _ := json.Marshal(obj.Message)
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":%d,"message":%s}}`, req.ID, obj.Code, msg), r
+	}
+	if res == "" {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d}`, req.ID), r
+	}
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, res), r
+}
+
+// newTestClient returns a client of the node at url with the chain id 1337,
+// signing with keys in turn. Its options can be changed until the first call.
+func newTestClient(t *testing.T, url string, keys ...*wallet.Key) *Client {
+	opts := &options{URL: url}
+	e, err := newEndpoint(url, opts)
+	require.NoError(t, err)
+	endpoints, err := newEndpointPool(strategyRoundRobin, []*endpoint{e})
+	require.NoError(t, err)
+
+	c := &Client{opts: opts, endpoints: endpoints, chainID: big.NewInt(1337)}
+	if len(keys) == 0 {
+		return c
+	}
+
+	accounts := make([]*account, len(keys))
+	for i, k := range keys {
+		accounts[i] = &account{key: k}
+	}
+	c.w = keys[0]
+	c.accounts, err = newAccountPool(accountStrategyRoundRobin, accounts)
+	require.NoError(t, err)
+
+	return c
+}
+
+// testReceipt returns the receipt of a successful transfer mined in block 1,
+// with the extra fields given.
+func testReceipt(from ethgo.Address, hash ethgo.Hash, extra ...string) string {
+	fields := append([]string{
+		fmt.Sprintf(`"from":"%s","transactionHash":"%s","blockHash":"%s"`, from, hash, ethgo.Hash{1}),
+		`"transactionIndex":"0x0","blockNumber":"0x1","gasUsed":"0x5208","cumulativeGasUsed":"0x5208"`,
+		`"logsBloom":"0x` + strings.Repeat("00", 256) + `","status":"0x1","logs":[]`,
+	}, extra...)
+	return "{" + strings.Join(fields, ",") + "}"
+}
@@ -0,0 +1,176 @@
+package ethereum
+
+import (
+	"errors"
+	"sync"
+)
+
+var errNonceManagerDisabled = errors.New("nonce manager is not enabled, set the nonceManager option")
+
+// nonceManager keeps the next nonce of the client account locally, so sending
+// a transaction doesn't require asking the node for it. The pending nonce is
+// fetched on first use and again after the node rejects a nonce.
+type nonceManager struct {
+	lock   sync.Mutex
+	fetch  func() (uint64, error)
+	nonce  uint64
+	synced bool
+	// epoch is incremented on every fetch, a nonce is only released in the
+	// epoch it was reserved in
+	epoch uint64
+}
+
+func newNonceManager(fetch func() (uint64, error)) *nonceManager {
+	return &nonceManager{fetch: fetch}
+}
+
+// sync fetches the pending nonce if it's not known, the lock must be held.
+func (n *nonceManager) sync() error {
+	if n.synced {
+		return nil
+	}
+
+	nonce, err := n.fetch()
+	if err != nil {
+		return err
+	}
+	n.nonce = nonce
+	n.synced = true
+	n.epoch++
+
+	return nil
+}
+
+// current returns the next nonce to be used.
+func (n *nonceManager) current() (uint64, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if err := n.sync(); err != nil {
+		return 0, err
+	}
+
+	return n.nonce, nil
+}
+
+// reserve returns the first of n consecutive nonces and skips them, for
+// transactions sent in parallel.
+func (n *nonceManager) reserve(count int) (uint64, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if err := n.sync(); err != nil {
+		return 0, err
+	}
+	first := n.nonce
+	n.nonce += uint64(count)
+
+	return first, nil
+}
+
+// next reserves the next nonce, returning it with the current epoch.
+func (n *nonceManager) next() (uint64, uint64, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if err := n.sync(); err != nil {
+		return 0, 0, err
+	}
+	n.nonce++
+
+	return n.nonce - 1, n.epoch, nil
+}
+
+// reset forgets the local nonce so it's fetched again on next use.
+func (n *nonceManager) reset() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.synced = false
+}
+
+// release gives back nonce, reserved by next for a transaction that wasn't
+// sent. It's reused if no other nonce was reserved since, otherwise the pending nonce is
+// fetched again on next use as the later ones would be stuck behind the gap.
+func (n *nonceManager) release(nonce, epoch uint64) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.synced && n.epoch == epoch && n.nonce == nonce+1 {
+		n.nonce = nonce
+		return
+	}
+	n.synced = false
+}
+
+// send calls fn with the next nonce, which is released if fn fails. The lock
+// isn't held while fn runs, so sends of the same account aren't serialized. If
+// the node rejects the nonce it's fetched again and fn is retried once.
+func (n *nonceManager) send(fn func(nonce uint64) (string, error)) (string, error) {
+	for retry := true; ; retry = false {
+		nonce, epoch, err := n.next()
+		if err != nil {
+			return "", err
+		}
+
+		h, err := fn(nonce)
+		if err == nil {
+			return h, nil
+		}
+
+		var e *RPCError
+		switch {
+		case isNonceError(err):
+			n.reset()
+			if retry {
+				continue
+			}
+		case errors.As(err, &e) && (e.Class == errClassTimeout || e.Class == errClassTransport):
+			// the node may have received the transaction
+			n.reset()
+		default:
+			n.release(nonce, epoch)
+		}
+		return "", err
+	}
+}
+
+// isNonceError returns true if the node rejected a transaction because of its nonce.
+func isNonceError(err error) bool {
+	var e *RPCError
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	return e.Class == errClassNonceTooLow || e.Class == errClassNonceTooHigh
+}
+
+// Nonce returns the next nonce the nonce manager will use for the account
+// given by from, an index or an address, or for the first one.
+func (c *Client) Nonce(from string) (uint64, error) {
+	if from == "" {
+		from = "0"
+	}
+	acc, err := c.accounts.lookup(from)
+	if err != nil {
+		return 0, err
+	}
+	if acc.nonces == nil {
+		return 0, errNonceManagerDisabled
+	}
+
+	return acc.nonces.current()
+}
+
+// ResetNonce makes the nonce manager fetch the pending nonce of every account
+// from the node on next use.
+func (c *Client) ResetNonce() error {
+	if c.accounts == nil || len(c.accounts.accounts) == 0 || c.accounts.accounts[0].nonces == nil {
+		return errNonceManagerDisabled
+	}
+
+	for _, acc := range c.accounts.accounts {
+		acc.nonces.reset()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package ethereum
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_nonceManager(t *testing.T) {
+	pending := uint64(5)
+	fetches := 0
+	n := newNonceManager(func() (uint64, error) {
+		fetches++
+		return pending, nil
+	})
+
+	send := func(nonce uint64) (string, error) {
+		if nonce != pending {
+			return "", &RPCError{Message: "nonce too low", Class: errClassNonceTooLow}
+		}
+		pending++
+		return "0x", nil
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := n.send(send)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, fetches)
+
+	nonce, err := n.current()
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), nonce)
+
+	// another sender used the account, the manager resyncs and retries
+	pending = 10
+	_, err = n.send(send)
+	require.NoError(t, err)
+	require.Equal(t, 2, fetches)
+	require.Equal(t, uint64(11), n.nonce)
+
+	_, err = n.send(func(uint64) (string, error) {
+		return "", errors.New("insufficient funds")
+	})
+	require.Error(t, err)
+	require.Equal(t, uint64(11), n.nonce)
+	require.Equal(t, 2, fetches)
+
+	// a failed send leaving a gap behind a later nonce resyncs
+	_, err = n.send(func(nonce uint64) (string, error) {
+		_, err := n.send(send)
+		require.NoError(t, err)
+		return "", errors.New("insufficient funds")
+	})
+	require.Error(t, err)
+	require.False(t, n.synced)
+
+	// so does a send that may have reached the node
+	_, err = n.current()
+	require.NoError(t, err)
+	_, err = n.send(func(uint64) (string, error) {
+		return "", &RPCError{Message: "timeout", Class: errClassTimeout}
+	})
+	require.Error(t, err)
+	require.False(t, n.synced)
+}
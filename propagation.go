@@ -0,0 +1,344 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/jsonrpc"
+	"go.k6.io/k6/event"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	defaultPropagationInterval = 100 * time.Millisecond
+	defaultPropagationTimeout  = time.Minute
+	// notifiedRetention is how long a transaction notified by an observer
+	// before being tracked is kept, as the notification can arrive before the
+	// node the transaction was sent to returns its hash.
+	notifiedRetention = 10 * time.Second
+)
+
+// propagationOptions defines configuration options for the propagation tracker.
+type propagationOptions struct {
+	// URLs are the nodes observing the propagation of transactions.
+	URLs []string `json:"urls"`
+	// PollInterval is the delay between eth_getTransactionByHash requests, defaults to 100ms.
+	PollInterval types.Duration `json:"pollInterval,omitempty"`
+	// Timeout is how long a transaction is watched for, defaults to 1m.
+	Timeout types.Duration `json:"timeout,omitempty"`
+	// Headers are sent with every request to the observers.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// PropagationTracker measures how long transactions take to be visible by a
+// set of observer nodes since they were accepted by the node they were sent to.
+type PropagationTracker struct {
+	vu       modules.VU
+	metrics  ethMetrics
+	interval time.Duration
+	timeout  time.Duration
+
+	observers []*observer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock    sync.Mutex
+	pending map[ethgo.Hash]*trackedTx
+	// notified holds the transactions notified by observers that aren't
+	// tracked yet
+	notified map[ethgo.Hash]*notifiedTx
+}
+
+// observer is a node polled for the tracked transactions. Observers with a
+// ws:// or wss:// url also notify them as they enter their mempool.
+type observer struct {
+	url    string
+	client *jsonrpc.Client
+	ws     *wsConn
+}
+
+type trackedTx struct {
+	sent time.Time
+	// seen holds the observers the transaction is visible by
+	seen map[string]bool
+}
+
+type notifiedTx struct {
+	at time.Time
+	// by holds when every observer notified the transaction
+	by map[string]time.Time
+}
+
+func (mi *ModuleInstance) NewPropagationTracker(call sobek.ConstructorCall) *sobek.Object {
+	rt := mi.vu.Runtime()
+
+	var optionsArg map[string]interface{}
+	if err := rt.ExportTo(call.Arguments[0], &optionsArg); err != nil {
+		common.Throw(rt, errors.New("unable to parse options object"))
+	}
+
+	var opts propagationOptions
+	if err := decodeOptions(optionsArg, &opts); err != nil {
+		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+	}
+	if len(opts.URLs) == 0 {
+		common.Throw(rt, errors.New("invalid options; reason: urls are required"))
+	}
+
+	pt, err := newPropagationTracker(mi.vu, mi.m, opts)
+	if err != nil {
+		common.Throw(rt, fmt.Errorf("invalid options; reason: %w", err))
+	}
+
+	if mi.vu.Events().Global != nil {
+		events := mi.vu.Events().Global
+		subID, ch := events.Subscribe(event.TestEnd, event.Exit)
+		go func() {
+			defer events.Unsubscribe(subID)
+			select {
+			case ev := <-ch:
+				pt.stop()
+				ev.Done()
+			case <-pt.ctx.Done():
+			}
+		}()
+	}
+
+	return rt.ToValue(pt).ToObject(rt)
+}
+
+func newPropagationTracker(vu modules.VU, m ethMetrics, opts propagationOptions) (*PropagationTracker, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pt := &PropagationTracker{
+		vu:       vu,
+		metrics:  m,
+		interval: time.Duration(opts.PollInterval),
+		timeout:  time.Duration(opts.Timeout),
+		ctx:      ctx,
+		cancel:   cancel,
+		pending:  map[ethgo.Hash]*trackedTx{},
+		notified: map[ethgo.Hash]*notifiedTx{},
+	}
+	if pt.interval <= 0 {
+		pt.interval = defaultPropagationInterval
+	}
+	if pt.timeout <= 0 {
+		pt.timeout = defaultPropagationTimeout
+	}
+
+	for _, url := range opts.URLs {
+		c, err := jsonrpc.NewClient(url, jsonrpc.WithHeaders(opts.Headers))
+		if err != nil {
+			pt.stop()
+			return nil, err
+		}
+		o := &observer{url: url, client: c}
+		pt.observers = append(pt.observers, o)
+
+		if isWebsocketURL(url) {
+			ws, err := dialWebsocket(url, opts.Headers)
+			if err != nil {
+				pt.stop()
+				return nil, err
+			}
+			o.ws = ws
+		}
+	}
+
+	for _, o := range pt.observers {
+		if o.ws != nil {
+			if _, err := o.ws.subscribe(pt.onPending(o), subNewPendingTransactions); err != nil {
+				pt.stop()
+				return nil, err
+			}
+		}
+		go pt.poll(o)
+	}
+
+	return pt, nil
+}
+
+// Track starts watching the transaction with the given hash, it should be
+// called as soon as the node it was sent to returns it.
+func (pt *PropagationTracker) Track(hash string) {
+	pt.track(ethgo.HexToHash(hash), time.Now())
+}
+
+// Watch tracks every transaction sent by client from then on, since the
+// moment it was sent.
+func (pt *PropagationTracker) Watch(client *Client) {
+	client.propagation.Store(pt)
+}
+
+// track starts watching the transaction hash sent at sent. The observers that
+// already notified it are reported right away.
+func (pt *PropagationTracker) track(hash ethgo.Hash, sent time.Time) {
+	pt.lock.Lock()
+	if _, ok := pt.pending[hash]; ok {
+		pt.lock.Unlock()
+		return
+	}
+	tx := &trackedTx{sent: sent, seen: map[string]bool{}}
+	pt.pending[hash] = tx
+
+	n := pt.notified[hash]
+	if n != nil {
+		delete(pt.notified, hash)
+		for url := range n.by {
+			tx.seen[url] = true
+		}
+		if len(tx.seen) == len(pt.observers) {
+			delete(pt.pending, hash)
+		}
+	}
+	pt.lock.Unlock()
+
+	if n == nil {
+		return
+	}
+	for url, at := range n.by {
+		d := at.Sub(sent)
+		if d < 0 {
+			d = 0
+		}
+		pt.report(url, d)
+	}
+}
+
+// Pending returns the number of transactions not yet seen by every observer.
+func (pt *PropagationTracker) Pending() int {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+
+	return len(pt.pending)
+}
+
+func (pt *PropagationTracker) stop() {
+	pt.cancel()
+	for _, o := range pt.observers {
+		if o.ws != nil {
+			o.ws.close()
+		}
+	}
+}
+
+// onPending returns the handler of the pending transactions notified by o.
+func (pt *PropagationTracker) onPending(o *observer) func([]byte) {
+	return func(b []byte) {
+		var hash ethgo.Hash
+		if err := json.Unmarshal(b, &hash); err != nil {
+			return
+		}
+		pt.seen(o, hash)
+	}
+}
+
+// poll looks up the transactions not seen by o yet until the tracker is stopped.
+func (pt *PropagationTracker) poll(o *observer) {
+	ticker := time.NewTicker(pt.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pt.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, hash := range pt.unseen(o) {
+			// kept raw as transactions of any type only need to be found
+			var tx json.RawMessage
+			if err := o.client.Call("eth_getTransactionByHash", &tx, hash); err != nil {
+				continue
+			}
+			if len(tx) > 0 && string(tx) != "null" {
+				pt.seen(o, hash)
+			}
+		}
+	}
+}
+
+// unseen returns the transactions not seen by o, dropping the ones watched
+// for longer than the timeout and the notified ones no longer kept.
+func (pt *PropagationTracker) unseen(o *observer) []ethgo.Hash {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+
+	for hash, n := range pt.notified {
+		if time.Since(n.at) > notifiedRetention {
+			delete(pt.notified, hash)
+		}
+	}
+
+	var hashes []ethgo.Hash
+	for hash, tx := range pt.pending {
+		if time.Since(tx.sent) > pt.timeout {
+			delete(pt.pending, hash)
+			continue
+		}
+		if !tx.seen[o.url] {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	return hashes
+}
+
+// seen reports the propagation time of hash to o the first time it's seen.
+// Transactions not tracked yet are kept until they are.
+func (pt *PropagationTracker) seen(o *observer, hash ethgo.Hash) {
+	now := time.Now()
+
+	pt.lock.Lock()
+	tx, ok := pt.pending[hash]
+	if !ok {
+		n := pt.notified[hash]
+		if n == nil {
+			n = &notifiedTx{at: now, by: map[string]time.Time{}}
+			pt.notified[hash] = n
+		}
+		if _, ok := n.by[o.url]; !ok {
+			n.by[o.url] = now
+		}
+		pt.lock.Unlock()
+		return
+	}
+	if tx.seen[o.url] {
+		pt.lock.Unlock()
+		return
+	}
+	tx.seen[o.url] = true
+	if len(tx.seen) == len(pt.observers) {
+		delete(pt.pending, hash)
+	}
+	pt.lock.Unlock()
+
+	pt.report(o.url, now.Sub(tx.sent))
+}
+
+func (pt *PropagationTracker) report(observer string, t time.Duration) {
+	// If we are testing vu is nil
+	if pt.vu == nil || pt.vu.State() == nil {
+		return
+	}
+
+	metrics.PushIfNotDone(pt.ctx, pt.vu.State().Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: pt.metrics.TxPropagation,
+			Tags:   metrics.NewRegistry().RootTagSet().With("observer", observer),
+		},
+		Value: float64(t / time.Millisecond),
+		Time:  time.Now(),
+	})
+}
@@ -0,0 +1,73 @@
+package ethereum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"go.k6.io/k6/lib/types"
+)
+
+func Test_PropagationTracker(t *testing.T) {
+	node := newTestNode(t)
+	node.handle("eth_getTransactionByHash", func(*rpcRequest) (string, error) {
+		// the transaction propagates on the third lookup
+		if node.calls["eth_getTransactionByHash"] >= 3 {
+			return `{"hash":"0x01"}`, nil
+		}
+		return "null", nil
+	})
+
+	pt, err := newPropagationTracker(nil, ethMetrics{}, propagationOptions{
+		URLs:         []string{node.url},
+		PollInterval: types.Duration(time.Millisecond),
+	})
+	require.NoError(t, err)
+	defer pt.stop()
+
+	pt.Track("0x01")
+	require.Equal(t, 1, pt.Pending())
+	require.Eventually(t, func() bool { return pt.Pending() == 0 }, time.Second, time.Millisecond)
+
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	require.Equal(t, map[string]int{"eth_getTransactionByHash": 3}, node.calls)
+}
+
+func Test_PropagationTrackerNotifiedFirst(t *testing.T) {
+	pt, err := newPropagationTracker(nil, ethMetrics{}, propagationOptions{
+		URLs:         []string{"http://localhost:8545"},
+		PollInterval: types.Duration(time.Hour),
+	})
+	require.NoError(t, err)
+	defer pt.stop()
+
+	// the observer notifies the transaction before the sender returns its hash
+	hash := ethgo.HexToHash("0x01").String()
+	pt.onPending(pt.observers[0])([]byte(`"` + hash + `"`))
+	require.Equal(t, 0, pt.Pending())
+	require.Len(t, pt.notified, 1)
+
+	pt.Track(hash)
+	require.Equal(t, 0, pt.Pending())
+	require.Empty(t, pt.notified)
+}
+
+func Test_PropagationTrackerWatch(t *testing.T) {
+	pt, err := newPropagationTracker(nil, ethMetrics{}, propagationOptions{
+		URLs:         []string{"http://localhost:8545"},
+		PollInterval: types.Duration(time.Hour),
+	})
+	require.NoError(t, err)
+	defer pt.stop()
+
+	c := &Client{opts: &options{}}
+	pt.Watch(c)
+
+	// transactions are tracked since they were sent
+	sent := time.Now().Add(-time.Second)
+	c.submitted(ethgo.HexToHash("0x01"), "legacy", sent)
+	require.Equal(t, 1, pt.Pending())
+	require.Equal(t, sent, pt.pending[ethgo.HexToHash("0x01")].sent)
+}
@@ -0,0 +1,253 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/umbracle/ethgo"
+)
+
+// minReplacementBump is the minimum fee increase in percent nodes require to
+// replace a pending transaction.
+const minReplacementBump = 10
+
+var (
+	errTransactionNotFound = errors.New("transaction not found")
+	errAlreadyMined        = errors.New("transaction already mined")
+)
+
+// ReplaceOptions are the options of SpeedUp and Cancel.
+type ReplaceOptions struct {
+	// BumpPercent is the fee increase of the replacement, at least and by
+	// default 10.
+	BumpPercent int `js:"bumpPercent"`
+}
+
+// replacements tracks the transactions replaced by SpeedUp and Cancel, every
+// version of a transaction shares the same chain.
+type replacements struct {
+	lock   sync.Mutex
+	chains map[ethgo.Hash]*replacementChain
+}
+
+type replacementChain struct {
+	hashes []ethgo.Hash
+}
+
+// add records replacement as the newest version of hash.
+func (r *replacements) add(hash, replacement ethgo.Hash) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.chains == nil {
+		r.chains = map[ethgo.Hash]*replacementChain{}
+	}
+	chain, ok := r.chains[hash]
+	if !ok {
+		chain = &replacementChain{hashes: []ethgo.Hash{hash}}
+		r.chains[hash] = chain
+	}
+	chain.hashes = append(chain.hashes, replacement)
+	r.chains[replacement] = chain
+}
+
+// versions returns every version of hash from the newest to the original.
+func (r *replacements) versions(hash ethgo.Hash) []ethgo.Hash {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	chain, ok := r.chains[hash]
+	if !ok {
+		return []ethgo.Hash{hash}
+	}
+
+	versions := make([]ethgo.Hash, len(chain.hashes))
+	for i, h := range chain.hashes {
+		versions[len(versions)-1-i] = h
+	}
+
+	return versions
+}
+
+// replacedReceipt returns the receipt of the version of hash that was mined.
+func (c *Client) replacedReceipt(hash string) (*Receipt, error) {
+	for _, h := range c.replacements.versions(ethgo.HexToHash(hash)) {
+		receipt, err := c.GetTransactionReceipt(h.String())
+		if !errors.Is(err, errReceiptNotFound) {
+			return receipt, err
+		}
+	}
+
+	return nil, errReceiptNotFound
+}
+
+// SpeedUp replaces the pending transaction with the given hash, sent by an
+// account of the client, by the same transaction with bumped fees. It returns the
+// hash of the replacement.
+func (c *Client) SpeedUp(hash string, opts ReplaceOptions) (string, error) {
+	return c.replace(ethgo.HexToHash(hash), opts, false)
+}
+
+// Cancel replaces the pending transaction with the given hash, sent by an
+// account of the client, by a transfer of nothing to the account itself with bumped
+// fees. It returns the hash of the replacement.
+func (c *Client) Cancel(hash string, opts ReplaceOptions) (string, error) {
+	return c.replace(ethgo.HexToHash(hash), opts, true)
+}
+
+func (c *Client) replace(hash ethgo.Hash, opts ReplaceOptions, cancel bool) (string, error) {
+	// the newest version is replaced, its fees are the ones to bump
+	latest := c.replacements.versions(hash)[0]
+	tx, err := c.pendingTransaction(latest)
+	if err != nil {
+		return "", err
+	}
+	acc, err := c.accounts.lookup(tx.From)
+	if err != nil {
+		return "", fmt.Errorf("transaction %s was not sent by an account of the client: %w", latest, err)
+	}
+
+	if cancel {
+		tx = Transaction{
+			From:      tx.From,
+			To:        tx.From,
+			Gas:       21000,
+			Nonce:     tx.Nonce,
+			GasPrice:  tx.GasPrice,
+			GasFeeCap: tx.GasFeeCap,
+			GasTipCap: tx.GasTipCap,
+		}
+	}
+
+	if err := c.bumpFees(&tx, opts.BumpPercent); err != nil {
+		return "", err
+	}
+
+	replacement, err := c.sendRawTransaction(acc.key, tx)
+	if err != nil {
+		return "", err
+	}
+	c.replacements.add(latest, ethgo.HexToHash(replacement))
+
+	return replacement, nil
+}
+
+// pendingTransaction returns the transaction with the given hash as sent,
+// failing if it's mined or if it's a blob transaction.
+func (c *Client) pendingTransaction(hash ethgo.Hash) (Transaction, error) {
+	raw, err := rpcCall(c, "eth_getTransactionByHash", func(ctx context.Context, e *endpoint) (json.RawMessage, error) {
+		var out json.RawMessage
+		err := e.call(ctx, "eth_getTransactionByHash", &out, hash)
+		return out, err
+	})
+	if err != nil {
+		return Transaction{}, c.wrapError("eth_getTransactionByHash", err)
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return Transaction{}, fmt.Errorf("%w: %s", errTransactionNotFound, hash)
+	}
+
+	var fields struct {
+		Type       string     `json:"type"`
+		BlockHash  *string    `json:"blockHash"`
+		AccessList AccessList `json:"accessList"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return Transaction{}, err
+	}
+	if fields.BlockHash != nil && *fields.BlockHash != (ethgo.Hash{}).String() {
+		return Transaction{}, fmt.Errorf("%w: %s", errAlreadyMined, hash)
+	}
+
+	var t ethgo.Transaction
+	if err := t.UnmarshalJSON(raw); err != nil {
+		return Transaction{}, err
+	}
+
+	tx := Transaction{
+		From:       t.From.String(),
+		Gas:        t.Gas,
+		Value:      Wei(weiString(t.Value)),
+		Nonce:      t.Nonce,
+		AccessList: fields.AccessList,
+		calldata:   t.Input,
+	}
+	if t.To != nil {
+		tx.To = t.To.String()
+	}
+
+	// the type is read from the node as legacy transactions may have a chain id
+	switch fields.Type {
+	case "", "0x0", "0x1":
+		tx.GasPrice = Wei(weiString(new(big.Int).SetUint64(t.GasPrice)))
+	case "0x2":
+		tx.GasFeeCap = Wei(weiString(t.MaxFeePerGas))
+		tx.GasTipCap = Wei(weiString(t.MaxPriorityFeePerGas))
+	default:
+		return Transaction{}, fmt.Errorf("transactions of type %s can't be replaced", fields.Type)
+	}
+
+	return tx, nil
+}
+
+// bumpFees raises the fees of tx by percent, at least by the minimum
+// replacement bump, or up to the fees of the fee oracle if they are higher.
+func (c *Client) bumpFees(tx *Transaction, percent int) error {
+	if percent < minReplacementBump {
+		percent = minReplacementBump
+	}
+
+	var current fees
+	if c.fees != nil {
+		f, err := c.fees.fees()
+		if err != nil {
+			return err
+		}
+		current = f
+	}
+
+	if !tx.GasFeeCap.IsZero() || !tx.GasTipCap.IsZero() {
+		feeCap, err := bumpedFee(tx.GasFeeCap, percent, current.feeCap)
+		if err != nil {
+			return err
+		}
+		tipCap, err := bumpedFee(tx.GasTipCap, percent, current.tipCap)
+		if err != nil {
+			return err
+		}
+		tx.GasFeeCap, tx.GasTipCap = feeCap, tipCap
+		return nil
+	}
+
+	gasPrice, err := bumpedFee(tx.GasPrice, percent, current.gasPrice)
+	if err != nil {
+		return err
+	}
+	tx.GasPrice = gasPrice
+
+	return nil
+}
+
+// bumpedFee returns fee raised by percent, rounded up, or floor if higher.
+func bumpedFee(fee Wei, percent int, floor *big.Int) (Wei, error) {
+	n, err := fee.Int()
+	if err != nil {
+		return "", err
+	}
+
+	bumped := new(big.Int).Mul(n, big.NewInt(int64(100+percent)))
+	bumped.Add(bumped, big.NewInt(99))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(n) <= 0 {
+		bumped.Add(n, big.NewInt(1))
+	}
+	if floor != nil && floor.Cmp(bumped) > 0 {
+		bumped.Set(floor)
+	}
+
+	return Wei(weiString(bumped)), nil
+}
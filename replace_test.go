@@ -0,0 +1,173 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/wallet"
+)
+
+// replaceNode keeps the transactions sent to it, all from the same account,
+// and mines the ones set in mined.
+type replaceNode struct {
+	*testNode
+	from  ethgo.Address
+	txs   map[ethgo.Hash]*ethgo.Transaction
+	mined map[ethgo.Hash]bool
+}
+
+func newReplaceNode(t *testing.T, from ethgo.Address, txs ...*ethgo.Transaction) *replaceNode {
+	n := &replaceNode{
+		testNode: newTestNode(t),
+		from:     from,
+		txs:      map[ethgo.Hash]*ethgo.Transaction{},
+		mined:    map[ethgo.Hash]bool{},
+	}
+	for _, t := range txs {
+		n.txs[t.Hash] = t
+	}
+
+	n.handle("eth_getTransactionByHash", func(req *rpcRequest) (string, error) {
+		if t, ok := n.txs[ethgo.HexToHash(req.params[0].(string))]; ok {
+			return n.txJSON(t), nil
+		}
+		return "null", nil
+	})
+	n.handle("eth_sendRawTransaction", func(req *rpcRequest) (string, error) {
+		raw, _ := hex.DecodeString(strings.TrimPrefix(req.params[0].(string), "0x"))
+		t := new(ethgo.Transaction)
+		if err := t.UnmarshalRLP(raw); err != nil {
+			return "", err
+		}
+		n.txs[t.Hash] = t
+		return `"` + t.Hash.String() + `"`, nil
+	})
+	n.handle("eth_getTransactionReceipt", func(req *rpcRequest) (string, error) {
+		if h := ethgo.HexToHash(req.params[0].(string)); n.mined[h] {
+			return testReceipt(n.from, h), nil
+		}
+		return "null", nil
+	})
+
+	return n
+}
+
+func (n *replaceNode) txJSON(t *ethgo.Transaction) string {
+	to := "null"
+	if t.To != nil {
+		to = `"` + t.To.String() + `"`
+	}
+	fees := ""
+	if t.Type == ethgo.TransactionDynamicFee {
+		fees = fmt.Sprintf(`"maxFeePerGas":"0x%x","maxPriorityFeePerGas":"0x%x",`, t.MaxFeePerGas, t.MaxPriorityFeePerGas)
+	}
+	block := `"blockHash":null`
+	if n.mined[t.Hash] {
+		block = fmt.Sprintf(`"blockHash":"%s","blockNumber":"0x1","transactionIndex":"0x0"`, ethgo.Hash{1})
+	}
+	value := t.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+
+	return fmt.Sprintf(`{"type":"0x%x","hash":"%s","from":"%s","to":%s,"input":"0x%s","value":"0x%x","gas":"0x%x",`+
+		`"gasPrice":"0x%x",%s"nonce":"0x%x","chainId":"0x539","v":"0x1","r":"0x1","s":"0x1",%s}`,
+		byte(t.Type), t.Hash, n.from, to, hex.EncodeToString(t.Input), value, t.Gas, t.GasPrice, fees, t.Nonce, block)
+}
+
+func Test_replace(t *testing.T) {
+	pk, _ := hex.DecodeString("42b6e34dc21598a807dc19d7784c71b2a7a01f6480dc6f58258f78e539f1a1fa")
+	wa, err := wallet.NewWalletFromPrivKey(pk)
+	require.NoError(t, err)
+
+	to := ethgo.HexToAddress("0x0000000000000000000000000000000000000042")
+	original := &ethgo.Transaction{
+		Hash:     ethgo.HexToHash("0x01"),
+		To:       &to,
+		Input:    []byte{1},
+		Value:    big.NewInt(7),
+		Gas:      50000,
+		GasPrice: 100,
+		Nonce:    5,
+	}
+	node := newReplaceNode(t, wa.Address(), original)
+	c := newTestClient(t, node.url, wa)
+
+	// bumps below the minimum are raised to it
+	h1, err := c.SpeedUp(original.Hash.String(), ReplaceOptions{BumpPercent: 1})
+	require.NoError(t, err)
+	sped := node.txs[ethgo.HexToHash(h1)]
+	require.Equal(t, uint64(110), sped.GasPrice)
+	require.Equal(t, original.Nonce, sped.Nonce)
+	require.Equal(t, to, *sped.To)
+	require.Equal(t, original.Input, sped.Input)
+	require.Equal(t, original.Value, sped.Value)
+	require.Equal(t, original.Gas, sped.Gas)
+
+	// the newest version is the one replaced
+	h2, err := c.Cancel(original.Hash.String(), ReplaceOptions{BumpPercent: 50})
+	require.NoError(t, err)
+	cancel := node.txs[ethgo.HexToHash(h2)]
+	require.Equal(t, uint64(165), cancel.GasPrice)
+	require.Equal(t, original.Nonce, cancel.Nonce)
+	require.Equal(t, wa.Address(), *cancel.To)
+	require.Zero(t, cancel.Value.Sign())
+	require.Empty(t, cancel.Input)
+	require.Equal(t, uint64(21000), cancel.Gas)
+
+	require.Equal(t, []ethgo.Hash{ethgo.HexToHash(h2), ethgo.HexToHash(h1), original.Hash}, c.replacements.versions(original.Hash))
+	require.Equal(t, c.replacements.versions(original.Hash), c.replacements.versions(ethgo.HexToHash(h1)))
+
+	// waiting for any version resolves with the mined one
+	_, err = c.replacedReceipt(original.Hash.String())
+	require.ErrorIs(t, err, errReceiptNotFound)
+	node.set(func() { node.mined[ethgo.HexToHash(h1)] = true })
+	receipt, err := c.waitForReceipt(context.Background(), original.Hash.String(), 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, ethgo.HexToHash(h1), receipt.TransactionHash)
+
+	// dynamic fee transactions have both caps bumped
+	dynamic := &ethgo.Transaction{
+		Type:                 ethgo.TransactionDynamicFee,
+		Hash:                 ethgo.HexToHash("0x02"),
+		To:                   &to,
+		Gas:                  21000,
+		Nonce:                6,
+		MaxFeePerGas:         big.NewInt(200),
+		MaxPriorityFeePerGas: big.NewInt(20),
+	}
+	node.set(func() { node.txs[dynamic.Hash] = dynamic })
+	h3, err := c.SpeedUp(dynamic.Hash.String(), ReplaceOptions{BumpPercent: 25})
+	require.NoError(t, err)
+	sped = node.txs[ethgo.HexToHash(h3)]
+	require.Equal(t, ethgo.TransactionDynamicFee, sped.Type)
+	require.Equal(t, big.NewInt(250), sped.MaxFeePerGas)
+	require.Equal(t, big.NewInt(25), sped.MaxPriorityFeePerGas)
+
+	// mined transactions can't be replaced
+	node.set(func() { node.mined[ethgo.HexToHash(h3)] = true })
+	_, err = c.SpeedUp(h3, ReplaceOptions{})
+	require.ErrorIs(t, err, errAlreadyMined)
+}
+
+func Test_bumpedFee(t *testing.T) {
+	fee, err := bumpedFee("1000", 10, nil)
+	require.NoError(t, err)
+	require.Equal(t, Wei("1100"), fee)
+
+	// rounded up
+	fee, err = bumpedFee("1", 10, nil)
+	require.NoError(t, err)
+	require.Equal(t, Wei("2"), fee)
+
+	// the fees of the oracle are used if higher
+	fee, err = bumpedFee("1000", 10, big.NewInt(2000))
+	require.NoError(t, err)
+	require.Equal(t, Wei("2000"), fee)
+}
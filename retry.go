@@ -0,0 +1,105 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultBackoff = 100 * time.Millisecond
+
+// rpcCall runs fn, a call to method, on an endpoint picked by the client
+// strategy. It applies the client timeout and retries transport errors and
+// timeouts with exponential backoff, each retry picking the endpoint again.
+func rpcCall[T any](c *Client, method string, fn func(ctx context.Context, e *endpoint) (T, error)) (T, error) {
+	return rpcCallWithTags(c, method, nil, fn)
+}
+
+// rpcCallWithTags is rpcCall adding tags to the request duration of every attempt.
+func rpcCallWithTags[T any](c *Client, method string, tags map[string]string, fn func(ctx context.Context, e *endpoint) (T, error)) (T, error) {
+	return callWithRetries(c, method, tags, true, fn)
+}
+
+// rpcCallOnce is rpcCall without retries, for calls that aren't idempotent.
+// A call that timed out may still reach the node, so sending a transaction the
+// node signs or builds again would send it twice.
+func rpcCallOnce[T any](c *Client, method string, fn func(ctx context.Context, e *endpoint) (T, error)) (T, error) {
+	return callWithRetries(c, method, nil, false, fn)
+}
+
+func callWithRetries[T any](c *Client, method string, tags map[string]string, retry bool, fn func(ctx context.Context, e *endpoint) (T, error)) (T, error) {
+	var timeout, backoff time.Duration
+	var retries int
+	if c.opts != nil {
+		timeout = time.Duration(c.opts.Timeout)
+		backoff = time.Duration(c.opts.Backoff)
+		retries = c.opts.Retries
+	}
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	if !retry {
+		retries = 0
+	}
+
+	ctx := context.Background()
+	if c.vu != nil {
+		ctx = c.vu.Context()
+	}
+
+	for attempt := 0; ; attempt++ {
+		e := c.endpoints.pick()
+		t := time.Now()
+		v, err := callWithTimeout(ctx, method, timeout, func(ctx context.Context) (T, error) {
+			return fn(ctx, e)
+		})
+		d := time.Since(t)
+		e.done(d, err)
+		c.reportMetricsFromStats(method, e.url, tags, d)
+
+		if err == nil || attempt >= retries || !isRetryable(err) {
+			return v, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return v, err
+		}
+		backoff *= 2
+	}
+}
+
+// callWithTimeout runs fn with a context that ends after timeout, and returns
+// a timeout error if it did. The context is the deadline of the request
+// itself, a call that timed out doesn't keep waiting for the node.
+func callWithTimeout[T any](ctx context.Context, method string, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	v, err := fn(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return v, fmt.Errorf("%s timed out after %s: %w", method, timeout, context.DeadlineExceeded)
+	}
+
+	return v, err
+}
+
+// isRetryable returns true for timeouts and errors reaching the node. The node
+// answered any other error, or it was returned before sending the request, and
+// retrying would fail the same way.
+func isRetryable(err error) bool {
+	var e *RPCError
+	if errors.As(err, &e) {
+		return e.Class == errClassTransport || e.Class == errClassTimeout
+	}
+
+	return isTimeout(err) || isNetworkError(err)
+}
@@ -0,0 +1,155 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+	"go.k6.io/k6/lib/types"
+)
+
+func Test_callWithTimeout(t *testing.T) {
+	_, err := callWithTimeout(context.Background(), "eth_blockNumber", 10*time.Millisecond, func(ctx context.Context) (uint64, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, errClassTimeout, newRPCError("eth_blockNumber", err, nil).Class)
+
+	n, err := callWithTimeout(context.Background(), "eth_blockNumber", time.Second, func(context.Context) (uint64, error) {
+		return 1, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), n)
+}
+
+func Test_rpcCallTimeout(t *testing.T) {
+	node := newTestNode(t)
+	node.handle("eth_blockNumber", func(*rpcRequest) (string, error) {
+		return "", errNoResponse
+	})
+	c := newTestClient(t, node.url)
+	c.opts.Timeout = types.Duration(50 * time.Millisecond)
+
+	// the node sees the request abandoned once it times out
+	_, err := c.BlockNumber()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Eventually(t, func() bool {
+		node.lock.Lock()
+		defer node.lock.Unlock()
+		return node.abandoned == 1
+	}, time.Second, time.Millisecond)
+
+	// over a websocket the request stops waiting for its response
+	c.endpoints = newTestClient(t, node.wsURL()).endpoints
+	e := c.endpoints.primary()
+	defer e.ws.close()
+
+	_, err = c.BlockNumber()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	e.ws.lock.Lock()
+	defer e.ws.lock.Unlock()
+	require.Empty(t, e.ws.pending)
+}
+
+func Test_rpcCall(t *testing.T) {
+	c := &Client{
+		opts:      &options{Retries: 2, Backoff: types.Duration(time.Millisecond)},
+		endpoints: &endpointPool{endpoints: []*endpoint{{url: "http://localhost:8545"}}},
+	}
+
+	calls := 0
+	n, err := rpcCall(c, "eth_blockNumber", func(context.Context, *endpoint) (uint64, error) {
+		calls++
+		if calls < 3 {
+			return 0, errors.New("connection refused")
+		}
+		return 1, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), n)
+	require.Equal(t, 3, calls)
+
+	// errors answered by the node aren't retried
+	calls = 0
+	_, err = rpcCall(c, "eth_blockNumber", func(context.Context, *endpoint) (uint64, error) {
+		calls++
+		return 0, &codec.ErrorObject{Code: -32000, Message: "nonce too low"}
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+
+	// nor malformed responses
+	calls = 0
+	_, err = rpcCall(c, "eth_blockNumber", func(context.Context, *endpoint) (uint64, error) {
+		calls++
+		var n uint64
+		return 0, json.Unmarshal([]byte("<html>bad gateway</html>"), &n)
+	})
+	var e *RPCError
+	require.ErrorAs(t, c.wrapError("eth_blockNumber", err), &e)
+	require.Equal(t, errClassRPC, e.Class)
+	require.Equal(t, 1, calls)
+
+	// neither are errors returned before sending the request
+	calls = 0
+	_, err = rpcCall(c, "eth_sendRawTransaction", func(context.Context, *endpoint) (uint64, error) {
+		calls++
+		return 0, errors.New("failed to encode arguments")
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+	require.True(t, c.endpoints.endpoints[0].healthy(time.Now()))
+}
+
+func Test_rpcCallOnce(t *testing.T) {
+	vu := newTestVU(t)
+	c := &Client{
+		vu:        vu,
+		opts:      &options{Retries: 2, Backoff: types.Duration(time.Hour)},
+		endpoints: &endpointPool{endpoints: []*endpoint{{url: "http://localhost:8545"}}},
+	}
+
+	calls := 0
+	_, err := rpcCallOnce(c, "eth_sendTransaction", func(context.Context, *endpoint) (uint64, error) {
+		calls++
+		return 0, errors.New("connection refused")
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+
+	// the backoff ends with the VU context
+	ctx, cancel := context.WithCancel(context.Background())
+	vu.ctx = ctx
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = rpcCall(c, "eth_blockNumber", func(context.Context, *endpoint) (uint64, error) {
+		return 0, errors.New("connection refused")
+	})
+	require.Error(t, err)
+}
+
+func Test_sendSigned(t *testing.T) {
+	node := newTestNode(t)
+	node.handle("eth_sendRawTransaction", func(*rpcRequest) (string, error) {
+		// the first attempt times out after reaching the node
+		if node.calls["eth_sendRawTransaction"] == 1 {
+			return "", errNoResponse
+		}
+		return "", &codec.ErrorObject{Code: -32000, Message: "already known"}
+	})
+	c := newTestClient(t, node.url)
+	c.opts.Timeout = types.Duration(50 * time.Millisecond)
+	c.opts.Retries = 1
+	c.opts.Backoff = types.Duration(time.Millisecond)
+
+	hash := ethgo.HexToHash("0x02")
+	h, err := c.sendSigned([]byte{1, 2, 3}, hash)
+	require.NoError(t, err)
+	require.Equal(t, hash, h)
+	require.Equal(t, 2, node.count("eth_sendRawTransaction"))
+}
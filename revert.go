@@ -0,0 +1,140 @@
+package ethereum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/abi"
+)
+
+var (
+	errorSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+
+	errorType = abi.MustNewType("tuple(string reason)")
+	panicType = abi.MustNewType("tuple(uint256 code)")
+)
+
+// panicReasons explains the solidity panic codes.
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "conversion to invalid enum value",
+	0x22: "access to incorrectly encoded storage byte array",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to zero initialized internal function",
+}
+
+// Revert is the decoded data of a reverted call, a solidity Error(string),
+// a Panic(uint256) or a custom error of the contract ABI.
+type Revert struct {
+	// Name is Error, Panic or the custom error name, empty if unknown
+	Name string
+	// Reason is the Error message or the Panic code explanation
+	Reason    string
+	PanicCode uint64
+	// Args are the arguments of a custom error
+	Args     map[string]interface{}
+	Selector string
+	Data     string
+}
+
+// String returns the reason of Error and Panic reverts, the name of custom
+// errors and the selector of unknown ones.
+func (r *Revert) String() string {
+	switch {
+	case r.Reason != "":
+		return r.Reason
+	case r.Name != "":
+		return r.Name
+	default:
+		return r.Selector
+	}
+}
+
+// tag returns the name of the error, or the selector of unknown ones, tagging
+// the ethereum_errors metric. Reasons aren't used, they have no bounds.
+func (r *Revert) tag() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Selector
+}
+
+// decodeRevert decodes the revert data returned in a JSON-RPC error, looking
+// up custom errors in contractABI if given. It returns nil without data.
+func decodeRevert(data interface{}, contractABI *abi.ABI) *Revert {
+	s, ok := data.(string)
+	if !ok {
+		return nil
+	}
+	// some clients prefix the data
+	s = strings.TrimPrefix(strings.TrimSpace(s), "Reverted ")
+
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(b) < 4 {
+		return nil
+	}
+
+	r := &Revert{
+		Selector: "0x" + hex.EncodeToString(b[:4]),
+		Data:     "0x" + hex.EncodeToString(b),
+	}
+
+	switch {
+	case bytes.Equal(b[:4], errorSelector):
+		out, err := abi.Decode(errorType, b[4:])
+		if err != nil {
+			return r
+		}
+		r.Name = "Error"
+		r.Reason, _ = out.(map[string]interface{})["reason"].(string)
+
+	case bytes.Equal(b[:4], panicSelector):
+		out, err := abi.Decode(panicType, b[4:])
+		if err != nil {
+			return r
+		}
+		code, _ := out.(map[string]interface{})["code"].(*big.Int)
+		if code == nil || !code.IsUint64() {
+			return r
+		}
+		r.Name = "Panic"
+		r.PanicCode = code.Uint64()
+		r.Reason = fmt.Sprintf("panic 0x%02x", r.PanicCode)
+		if reason, ok := panicReasons[r.PanicCode]; ok {
+			r.Reason += ": " + reason
+		}
+
+	case contractABI != nil:
+		for _, e := range contractABI.Errors {
+			if !bytes.Equal(b[:4], errorID(e)) {
+				continue
+			}
+			r.Name = e.Name
+			out, err := abi.Decode(e.Inputs, b[4:])
+			if err != nil {
+				return r
+			}
+			if args, ok := bigIntsToStrings(out).(map[string]interface{}); ok {
+				r.Args = args
+			}
+			break
+		}
+	}
+
+	return r
+}
+
+// errorID returns the selector of a custom error.
+func errorID(e *abi.Error) []byte {
+	return ethgo.Keccak256([]byte(abi.NewEventFromType(e.Name, e.Inputs).Sig()))[:4]
+}
@@ -0,0 +1,61 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo/abi"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+)
+
+func revertData(t *testing.T, selector []byte, typ string, args map[string]interface{}) string {
+	t.Helper()
+
+	data, err := abi.Encode(args, abi.MustNewType(typ))
+	require.NoError(t, err)
+
+	return "0x" + hex.EncodeToString(append(selector, data...))
+}
+
+func Test_decodeRevert(t *testing.T) {
+	data := revertData(t, errorSelector, "tuple(string reason)", map[string]interface{}{"reason": "not owner"})
+	r := decodeRevert(data, nil)
+	require.Equal(t, "Error", r.Name)
+	require.Equal(t, "not owner", r.Reason)
+	require.Equal(t, "Error", r.tag())
+
+	data = revertData(t, panicSelector, "tuple(uint256 code)", map[string]interface{}{"code": big.NewInt(0x11)})
+	r = decodeRevert(data, nil)
+	require.Equal(t, "Panic", r.Name)
+	require.Equal(t, uint64(0x11), r.PanicCode)
+	require.Equal(t, "panic 0x11: arithmetic underflow or overflow", r.String())
+
+	contractABI, err := abi.NewABIFromList([]string{
+		"error InsufficientBalance(uint256 available, uint256 required)",
+	})
+	require.NoError(t, err)
+	custom := contractABI.Errors["InsufficientBalance"]
+
+	data = revertData(t, errorID(custom), "tuple(uint256 available, uint256 required)", map[string]interface{}{
+		"available": big.NewInt(1),
+		"required":  big.NewInt(2),
+	})
+	r = decodeRevert(data, contractABI)
+	require.Equal(t, "InsufficientBalance", r.Name)
+	require.Equal(t, map[string]interface{}{"available": "1", "required": "2"}, r.Args)
+
+	// without the abi only the selector is known
+	r = decodeRevert(data, nil)
+	require.Equal(t, "", r.Name)
+	require.Equal(t, hex.EncodeToString(errorID(custom)), r.String()[2:])
+	require.Equal(t, r.Selector, r.tag())
+
+	require.Nil(t, decodeRevert(nil, nil))
+	require.Nil(t, decodeRevert("0x", nil))
+
+	e := newRPCError("eth_estimateGas", &codec.ErrorObject{Code: 3, Message: "execution reverted", Data: data}, contractABI)
+	require.Equal(t, errClassExecutionReverted, e.Class)
+	require.Equal(t, "eth_estimateGas: execution reverted: InsufficientBalance", e.Error())
+}
@@ -0,0 +1,450 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/grafana/sobek"
+	"github.com/umbracle/ethgo"
+	"github.com/umbracle/ethgo/jsonrpc/codec"
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	subNewHeads               = "newHeads"
+	subLogs                   = "logs"
+	subNewPendingTransactions = "newPendingTransactions"
+)
+
+// subscriptionBuffer is the number of notifications buffered for a
+// subscription whose callback is busy.
+const subscriptionBuffer = 1024
+
+var errConnClosed = errors.New("websocket connection closed")
+
+// isWebsocketURL returns true if the url uses the ws:// or wss:// scheme.
+func isWebsocketURL(url string) bool {
+	return strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://")
+}
+
+// wsMessage is any message received over the websocket, either a response to
+// a request or an eth_subscription notification.
+type wsMessage struct {
+	ID     uint64              `json:"id"`
+	Method string              `json:"method"`
+	Result json.RawMessage     `json:"result"`
+	Error  *codec.ErrorObject  `json:"error,omitempty"`
+	Params *codec.Subscription `json:"params,omitempty"`
+}
+
+type wsRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// wsConn is a persistent websocket connection used to deliver eth_subscribe
+// notifications to a client. Notification handlers are called by the reader
+// and must not block.
+type wsConn struct {
+	url  string
+	conn *websocket.Conn
+	// done is closed once the connection is closed
+	done chan struct{}
+
+	writeLock sync.Mutex
+
+	lock    sync.Mutex
+	seq     uint64
+	pending map[uint64]chan *wsMessage
+	// onSubscribe holds the handlers of in-flight eth_subscribe requests, they
+	// are installed by the reader before any notification can be dispatched.
+	onSubscribe map[uint64]func([]byte)
+	subs        map[string]func([]byte)
+	closed      bool
+}
+
+func dialWebsocket(url string, headers map[string]string) (*wsConn, error) {
+	h := http.Header{}
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, h)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &wsConn{
+		url:         url,
+		conn:        conn,
+		done:        make(chan struct{}),
+		pending:     map[uint64]chan *wsMessage{},
+		onSubscribe: map[uint64]func([]byte){},
+		subs:        map[string]func([]byte){},
+	}
+	go w.listen()
+
+	return w, nil
+}
+
+func (w *wsConn) listen() {
+	for {
+		_, buf, err := w.conn.ReadMessage()
+		if err != nil {
+			w.close()
+			return
+		}
+
+		var msg wsMessage
+		if err := json.Unmarshal(buf, &msg); err != nil {
+			continue
+		}
+
+		if msg.Method == "eth_subscription" && msg.Params != nil {
+			w.lock.Lock()
+			handler := w.subs[msg.Params.ID]
+			w.lock.Unlock()
+			if handler != nil {
+				handler(msg.Params.Result)
+			}
+			continue
+		}
+
+		w.lock.Lock()
+		ch, ok := w.pending[msg.ID]
+		delete(w.pending, msg.ID)
+		if handler, ok := w.onSubscribe[msg.ID]; ok {
+			delete(w.onSubscribe, msg.ID)
+			var id string
+			if msg.Error == nil && json.Unmarshal(msg.Result, &id) == nil {
+				w.subs[id] = handler
+			}
+		}
+		w.lock.Unlock()
+
+		if ok {
+			ch <- &msg
+		}
+	}
+}
+
+func (w *wsConn) close() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return
+	}
+	w.closed = true
+	w.conn.Close()
+	close(w.done)
+
+	for id, ch := range w.pending {
+		close(ch)
+		delete(w.pending, id)
+	}
+}
+
+func (w *wsConn) call(ctx context.Context, method string, out interface{}, onSubscribe func([]byte), params ...interface{}) error {
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return errConnClosed
+	}
+	w.seq++
+	id := w.seq
+	ch := make(chan *wsMessage, 1)
+	w.pending[id] = ch
+	if onSubscribe != nil {
+		w.onSubscribe[id] = onSubscribe
+	}
+	w.lock.Unlock()
+
+	if params == nil {
+		params = []interface{}{}
+	}
+	raw, err := json.Marshal(wsRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	w.writeLock.Lock()
+	err = w.conn.WriteMessage(websocket.TextMessage, raw)
+	w.writeLock.Unlock()
+	if err != nil {
+		w.close()
+		return err
+	}
+
+	var msg *wsMessage
+	select {
+	case msg = <-ch:
+	case <-ctx.Done():
+		w.lock.Lock()
+		delete(w.pending, id)
+		delete(w.onSubscribe, id)
+		w.lock.Unlock()
+		return ctx.Err()
+	}
+	if msg == nil {
+		return errConnClosed
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+
+	return json.Unmarshal(msg.Result, out)
+}
+
+// subscribe issues eth_subscribe and routes its notifications to handler.
+func (w *wsConn) subscribe(handler func([]byte), params ...interface{}) (string, error) {
+	var id string
+	if err := w.call(context.Background(), "eth_subscribe", &id, handler, params...); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// unsubscribe issues eth_unsubscribe and stops routing notifications for id.
+func (w *wsConn) unsubscribe(id string) error {
+	w.lock.Lock()
+	delete(w.subs, id)
+	w.lock.Unlock()
+
+	var ok bool
+	return w.call(context.Background(), "eth_unsubscribe", &ok, nil, id)
+}
+
+// subscription is an active eth_subscribe subscription whose notifications
+// are delivered to a JS callback on the event loop.
+type subscription struct {
+	id            string
+	kind          string
+	callback      sobek.Callable
+	notifications chan []byte
+	done          chan struct{}
+	once          sync.Once
+	// dropped counts the notifications received while the buffer was full
+	dropped uint64
+}
+
+func (s *subscription) stop() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// closed returns true once the subscription is stopped.
+func (s *subscription) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliver buffers a notification without blocking the reader of the
+// connection, it's dropped if the callback doesn't keep up.
+func (s *subscription) deliver(b []byte) {
+	select {
+	case s.notifications <- b:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// Subscribe creates a new subscription of the given kind ("newHeads", "logs" or
+// "newPendingTransactions") and calls callback on every notification received.
+// It returns the subscription id to be used with Unsubscribe.
+func (c *Client) Subscribe(kind string, params interface{}, callback sobek.Callable) (string, error) {
+	if c.ws == nil {
+		return "", fmt.Errorf("subscriptions require a ws:// or wss:// url")
+	}
+	if callback == nil {
+		return "", fmt.Errorf("a callback is required")
+	}
+
+	switch kind {
+	case subNewHeads, subLogs, subNewPendingTransactions:
+	default:
+		return "", fmt.Errorf("unsupported subscription %q", kind)
+	}
+
+	s := &subscription{
+		kind:          kind,
+		callback:      callback,
+		notifications: make(chan []byte, subscriptionBuffer),
+		done:          make(chan struct{}),
+	}
+
+	args := []interface{}{kind}
+	if params != nil {
+		args = append(args, params)
+	}
+
+	// not retried, a subscription created after a timeout would feed the
+	// callback twice
+	t := time.Now()
+	id, err := c.ws.subscribe(s.deliver, args...)
+	c.reportMetricsFromStats("eth_subscribe", c.ws.url, nil, time.Since(t))
+	if err != nil {
+		return "", c.wrapError("eth_subscribe", err)
+	}
+	s.id = id
+
+	c.subsLock.Lock()
+	c.subs[id] = s
+	c.subsLock.Unlock()
+
+	go c.dispatch(s, c.vu.RegisterCallback())
+
+	return id, nil
+}
+
+// Unsubscribe cancels the subscription with the given id.
+func (c *Client) Unsubscribe(id string) (bool, error) {
+	c.subsLock.Lock()
+	s, ok := c.subs[id]
+	delete(c.subs, id)
+	c.subsLock.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+	s.stop()
+
+	if err := c.ws.unsubscribe(id); err != nil && !errors.Is(err, errConnClosed) {
+		return false, c.wrapError("eth_unsubscribe", err)
+	}
+
+	return true, nil
+}
+
+// dispatch delivers the notifications of s to its callback on the event loop.
+// A callback is kept registered while the subscription is active so the
+// iteration does not end before Unsubscribe is called or the connection is
+// closed. Once the subscription is closed, even by its own callback, nothing
+// else is delivered and no callback is registered again.
+func (c *Client) dispatch(s *subscription, enqueue func(func() error)) {
+	ctx := c.vu.Context()
+	defer func() {
+		if n := atomic.SwapUint64(&s.dropped, 0); n > 0 {
+			c.reportDropped(s.kind, n)
+		}
+	}()
+
+	for {
+		// a closed subscription wins over the notifications still buffered
+		if s.closed() {
+			enqueue(func() error { return nil })
+			return
+		}
+
+		select {
+		case b := <-s.notifications:
+			if n := atomic.SwapUint64(&s.dropped, 0); n > 0 {
+				c.reportDropped(s.kind, n)
+			}
+			next := make(chan func(func() error), 1)
+			enqueue(func() error {
+				if s.closed() {
+					next <- nil
+					return nil
+				}
+				if v, err := decodeNotification(s.kind, b); err == nil {
+					if _, err := s.callback(sobek.Undefined(), c.vu.Runtime().ToValue(v)); err != nil {
+						s.stop()
+						next <- nil
+						return err
+					}
+				}
+				// the callback may have unsubscribed
+				if s.closed() {
+					next <- nil
+					return nil
+				}
+				next <- c.vu.RegisterCallback()
+				return nil
+			})
+
+			select {
+			case enqueue = <-next:
+			case <-ctx.Done():
+				return
+			}
+			if enqueue == nil {
+				_, _ = c.Unsubscribe(s.id)
+				return
+			}
+		case <-s.done:
+			enqueue(func() error { return nil })
+			return
+		case <-c.ws.done:
+			c.subsLock.Lock()
+			delete(c.subs, s.id)
+			c.subsLock.Unlock()
+			s.stop()
+			enqueue(func() error { return nil })
+			return
+		case <-ctx.Done():
+			s.stop()
+			enqueue(func() error { return nil })
+			return
+		}
+	}
+}
+
+// reportDropped counts the notifications of a subscription of the given kind
+// dropped because its callback didn't keep up.
+func (c *Client) reportDropped(kind string, n uint64) {
+	// If we are testing vu is nil
+	if c.vu == nil || c.vu.State() == nil {
+		return
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), c.vu.State().Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: c.metrics.SubDropped,
+			Tags:   metrics.NewRegistry().RootTagSet().With("kind", kind),
+		},
+		Value: float64(n),
+		Time:  time.Now(),
+	})
+}
+
+// decodeNotification decodes the result of a notification into the same types
+// returned by the rest of the client API.
+func decodeNotification(kind string, b []byte) (interface{}, error) {
+	switch kind {
+	case subNewHeads:
+		var block ethgo.Block
+		if err := json.Unmarshal(b, &block); err != nil {
+			return nil, err
+		}
+		return &block, nil
+	case subLogs:
+		var log ethgo.Log
+		if err := json.Unmarshal(b, &log); err != nil {
+			return nil, err
+		}
+		return &log, nil
+	default:
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
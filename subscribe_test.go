@@ -0,0 +1,200 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/eventloop"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+)
+
+// testVU is a VU running scripts on an event loop, in the init context until
+// its state is set.
+type testVU struct {
+	ctx     context.Context
+	rt      *sobek.Runtime
+	loop    *eventloop.EventLoop
+	initEnv *common.InitEnvironment
+	state   atomic.Pointer[lib.State]
+}
+
+func newTestVU(t *testing.T) *testVU {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	vu := &testVU{
+		ctx: ctx,
+		rt:  sobek.New(),
+		initEnv: &common.InitEnvironment{
+			TestPreInitState: &lib.TestPreInitState{Registry: metrics.NewRegistry()},
+		},
+	}
+	vu.rt.SetFieldNameMapper(common.FieldNameMapper{})
+	vu.loop = eventloop.New(vu)
+
+	return vu
+}
+
+func (vu *testVU) Context() context.Context { return vu.ctx }
+func (vu *testVU) Events() common.Events    { return common.Events{} }
+func (vu *testVU) Runtime() *sobek.Runtime  { return vu.rt }
+func (vu *testVU) State() *lib.State        { return vu.state.Load() }
+
+func (vu *testVU) InitEnv() *common.InitEnvironment {
+	if vu.State() != nil {
+		return nil
+	}
+	return vu.initEnv
+}
+
+func (vu *testVU) RegisterCallback() func(func() error) {
+	return vu.loop.RegisterCallback()
+}
+
+// run runs code on the event loop until every callback registered is done.
+func (vu *testVU) run(code string) error {
+	defer vu.loop.WaitOnRegistered()
+
+	return vu.loop.Start(func() error {
+		_, err := vu.rt.RunString(code)
+		return err
+	})
+}
+
+// newSubscriptionNode returns a node answering subscriptions with the id 0x1
+// followed by n notifications. With closeOnSubscribe the connection is closed
+// right after.
+func newSubscriptionNode(t *testing.T, n int, closeOnSubscribe bool) *testNode {
+	node := newTestNode(t)
+	node.handle("eth_subscribe", func(req *rpcRequest) (string, error) {
+		for i := 0; i < n; i++ {
+			req.notify(fmt.Sprintf(`"0x%x"`, i))
+		}
+		req.close = closeOnSubscribe
+		return `"0x1"`, nil
+	})
+	node.handle("eth_unsubscribe", result("true"))
+	node.handle("eth_blockNumber", result(`"0x1"`))
+	return node
+}
+
+func Test_wsConnSlowSubscription(t *testing.T) {
+	node := newSubscriptionNode(t, 10, false)
+
+	w, err := dialWebsocket(node.wsURL(), nil)
+	require.NoError(t, err)
+	defer w.close()
+
+	// a subscription whose callback never consumes its notifications
+	s := &subscription{notifications: make(chan []byte, 1), done: make(chan struct{})}
+	id, err := w.subscribe(s.deliver, subNewPendingTransactions)
+	require.NoError(t, err)
+	require.Equal(t, "0x1", id)
+
+	// the notifications sent before the response were read without blocking
+	var out string
+	require.NoError(t, w.call(context.Background(), "eth_blockNumber", &out, nil))
+	require.Equal(t, "0x1", out)
+	require.Len(t, s.notifications, 1)
+	require.Equal(t, uint64(9), atomic.LoadUint64(&s.dropped))
+}
+
+func Test_subscriptionEndsOnClose(t *testing.T) {
+	node := newSubscriptionNode(t, 0, true)
+
+	w, err := dialWebsocket(node.wsURL(), nil)
+	require.NoError(t, err)
+	defer w.close()
+
+	vu := newTestVU(t)
+	c := &Client{vu: vu, ws: w, subs: map[string]*subscription{}}
+	require.NoError(t, vu.rt.Set("client", c))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- vu.run(`client.subscribe("newHeads", null, () => {})`)
+	}()
+
+	// the iteration ends once the connection is closed
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscription kept the iteration running after the connection was closed")
+	}
+	require.Empty(t, c.subs)
+	require.Equal(t, 1, node.count("eth_subscribe"))
+
+	// subscribing again fails without retrying
+	_, err = c.Subscribe(subNewHeads, nil, func(sobek.Value, ...sobek.Value) (sobek.Value, error) {
+		return sobek.Undefined(), nil
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, node.count("eth_subscribe"))
+}
+
+func Test_subscriptionUnsubscribeInCallback(t *testing.T) {
+	node := newSubscriptionNode(t, 10, false)
+
+	w, err := dialWebsocket(node.wsURL(), nil)
+	require.NoError(t, err)
+	defer w.close()
+
+	vu := newTestVU(t)
+	c := &Client{vu: vu, ws: w, subs: map[string]*subscription{}}
+	require.NoError(t, vu.rt.Set("client", c))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- vu.run(`
+			var calls = 0;
+			const id = client.subscribe("newPendingTransactions", null, () => {
+				calls++;
+				client.unsubscribe(id);
+			});`)
+	}()
+
+	// the iteration ends with no callback left registered
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscription kept the iteration running after unsubscribing")
+	}
+	// nothing is delivered after unsubscribing, even if buffered
+	require.Equal(t, int64(1), vu.rt.Get("calls").ToInteger())
+	c.subsLock.Lock()
+	defer c.subsLock.Unlock()
+	require.Empty(t, c.subs)
+}
+
+func Test_clientClosesWebsocket(t *testing.T) {
+	node := newSubscriptionNode(t, 0, false)
+
+	vu := newTestVU(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	vu.ctx = ctx
+	mi := (&EthRoot{}).NewModuleInstance(vu).(*ModuleInstance)
+	require.NoError(t, vu.rt.Set("Client", mi.NewClient))
+
+	v, err := vu.rt.RunString(`new Client({url: "` + node.wsURL() + `", blockMonitor: false})`)
+	require.NoError(t, err)
+	c, ok := v.Export().(*Client)
+	require.True(t, ok)
+	require.NotNil(t, c.ws)
+
+	// the connection is closed with the VU
+	cancel()
+	select {
+	case <-c.ws.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the websocket connection was left open after the VU was done")
+	}
+}
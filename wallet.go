@@ -22,18 +22,28 @@ func init() {
 
 // GenerateKey key creates a random key
 func (w *Wallet) GenerateKey() (*Key, error) {
+	return newKey()
+}
+
+// newKey generates a random account.
+func newKey() (*Key, error) {
 	k, err := wallet.GenerateKey()
 	if err != nil {
 		return nil, err
 	}
+
+	return newKeyFrom(k)
+}
+
+// newKeyFrom returns the private key and address of k.
+func newKeyFrom(k *wallet.Key) (*Key, error) {
 	pk, err := k.MarshallPrivateKey()
 	if err != nil {
 		return nil, err
 	}
-	pks := hex.EncodeToString(pk)
 
 	return &Key{
-		PrivateKey: pks,
+		PrivateKey: hex.EncodeToString(pk),
 		Address:    k.Address().String(),
-	}, err
+	}, nil
 }
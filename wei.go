@@ -0,0 +1,125 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Wei is a wei denominated amount as given from JS. It accepts numbers, decimal
+// strings and 0x prefixed hex strings, so amounts above Number.MAX_SAFE_INTEGER
+// can be passed as strings without losing precision.
+type Wei string
+
+// UnmarshalJSON accepts amounts given as JSON numbers or strings.
+func (w *Wei) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*w = Wei(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("invalid wei amount %s", b)
+	}
+	*w = Wei(n)
+
+	return nil
+}
+
+// Int returns the amount as a big.Int, an empty amount is zero. Negative
+// amounts are rejected, they would be encoded as their absolute value.
+func (w Wei) Int() (*big.Int, error) {
+	n, err := w.parse()
+	if err != nil {
+		return nil, err
+	}
+	if n.Sign() < 0 {
+		return nil, fmt.Errorf("negative wei amount %s", n)
+	}
+
+	return n, nil
+}
+
+func (w Wei) parse() (*big.Int, error) {
+	s := strings.TrimSpace(string(w))
+	if s == "" {
+		return new(big.Int), nil
+	}
+
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		if n, ok := new(big.Int).SetString(s[2:], 16); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("invalid wei amount %q", s)
+	}
+
+	if n, ok := new(big.Int).SetString(s, 10); ok {
+		return n, nil
+	}
+
+	// numbers above 1e21 are stringified by JS using the exponent notation
+	f, ok := new(big.Float).SetPrec(256).SetString(s)
+	if !ok || !f.IsInt() {
+		return nil, fmt.Errorf("invalid wei amount %q", s)
+	}
+	n, _ := f.Int(nil)
+
+	return n, nil
+}
+
+// Uint64 returns the amount as an uint64 failing if it doesn't fit.
+func (w Wei) Uint64() (uint64, error) {
+	n, err := w.Int()
+	if err != nil {
+		return 0, err
+	}
+	if !n.IsUint64() {
+		return 0, fmt.Errorf("wei amount %s out of range", n)
+	}
+
+	return n.Uint64(), nil
+}
+
+// IsZero returns true if the amount is empty or zero.
+func (w Wei) IsZero() bool {
+	n, err := w.Int()
+	return err == nil && n.Sign() == 0
+}
+
+// weiString returns n as a decimal string to be handed to JS.
+func weiString(n *big.Int) string {
+	if n == nil {
+		return "0"
+	}
+	return n.String()
+}
+
+// bigIntsToStrings replaces the big.Int values of a decoded contract output with
+// decimal strings, recursing into tuples and arrays.
+func bigIntsToStrings(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *big.Int:
+		return weiString(v)
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = bigIntsToStrings(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = bigIntsToStrings(e)
+		}
+		return v
+	case []*big.Int:
+		out := make([]string, len(v))
+		for i, e := range v {
+			out[i] = weiString(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
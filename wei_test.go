@@ -0,0 +1,34 @@
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WeiInt(t *testing.T) {
+	cases := map[Wei]string{
+		"":                         "0",
+		"50000000000000000":        "50000000000000000",
+		"123456789012345678901234": "123456789012345678901234",
+		"0x de0b6b3a7640000":       "",
+		"0xde0b6b3a7640000":        "1000000000000000000",
+		"1e+21":                    "1000000000000000000000",
+		"0.5":                      "",
+		"-1000000000000000000":     "",
+		"-0x1":                     "",
+	}
+
+	for in, expected := range cases {
+		n, err := in.Int()
+		if expected == "" {
+			require.Error(t, err, in)
+			continue
+		}
+		require.NoError(t, err, in)
+		require.Equal(t, expected, n.String())
+	}
+
+	_, err := Wei("18446744073709551616").Uint64()
+	require.Error(t, err)
+}